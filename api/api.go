@@ -0,0 +1,9 @@
+// Package api embeds the OpenAPI description of the HTTP API.
+package api
+
+import _ "embed"
+
+// Spec is the OpenAPI 3 document the server validates requests against.
+//
+//go:embed api.yaml
+var Spec []byte
@@ -3,29 +3,17 @@ package main
 import (
 	"context"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 	_ "github.com/lib/pq"
 
+	"github.com/zanzhit/flat-seller/internal/app"
 	"github.com/zanzhit/flat-seller/internal/config"
-	authhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/auth"
-	flathandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/flat"
-	househandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/house"
-	authmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/auth"
-	"github.com/zanzhit/flat-seller/internal/http-server/middleware/logger"
 	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
-	authservice "github.com/zanzhit/flat-seller/internal/services/auth"
-	flatservice "github.com/zanzhit/flat-seller/internal/services/flat"
 	"github.com/zanzhit/flat-seller/internal/storage/postgres"
-	authstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/auth"
-	flatstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/flat"
-	housestorage "github.com/zanzhit/flat-seller/internal/storage/postgres/house"
 )
 
 const (
@@ -51,51 +39,17 @@ func main() {
 		panic(err)
 	}
 
-	router := chi.NewRouter()
-
-	router.Use(middleware.RequestID)
-	router.Use(logger.New(log))
-	router.Use(middleware.Recoverer)
-	router.Use(middleware.URLFormat)
-
-	authstorage := authstorage.New(storage)
-	authService := authservice.New(log, authstorage, authstorage, cfg.TokenTTL, cfg.Secret)
-	authhandler := authhandler.New(log, authService)
-
-	houseStorage := housestorage.New(storage)
-	houseHandler := househandler.New(log, houseStorage)
-
-	flatStorage := flatstorage.New(storage)
-	flatService := flatservice.New(log, flatStorage)
-	flatHandler := flathandler.New(log, flatService)
-
-	router.Post("/register", authhandler.RegisterNewUser)
-	router.Post("/login", authhandler.Login)
-	router.Post("/dummyLogin", authhandler.DummyLogin)
-
-	router.With(authmid.JWTAuth(cfg.Secret)).Group(func(r chi.Router) {
-		r.Post("/flat/create", flatHandler.SaveFlat)
-		r.With(authmid.AdminRequired).Post("/flat/update", flatHandler.UpdateFlat)
-		r.With(authmid.AdminRequired).Post("/house/create", houseHandler.SaveHouse)
-		r.Get("/house/{id}", houseHandler.House)
-	})
-
-	log.Info("starting http server", slog.String("address", cfg.Address))
+	application, err := app.New(log, cfg, storage)
+	if err != nil {
+		panic(err)
+	}
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
-	srv := http.Server{
-		Addr:         cfg.Address,
-		Handler:      router,
-		ReadTimeout:  cfg.Timeout,
-		WriteTimeout: cfg.Timeout,
-		IdleTimeout:  cfg.IdleTimeout,
-	}
-
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			log.Error("failed to start server")
+		if err := application.Run(); err != nil {
+			log.Error("failed to start server", sl.Err(err))
 		}
 	}()
 
@@ -105,10 +59,8 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := application.Stop(ctx); err != nil {
 		log.Error("failed to stop server", sl.Err(err))
-
-		return
 	}
 
 	if err := storage.Close(); err != nil {
@@ -4,42 +4,172 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/zanzhit/flat-seller/internal/config"
 )
 
+const usage = `usage: migrator [flags] <command> [args]
+
+commands:
+  up [n]        apply all or n pending migrations
+  down [n]      roll back n migrations (1 by default)
+  goto v        migrate up or down to version v
+  force v       set version v without running migrations, clears the dirty flag
+  version       print the current version
+  status        list migrations and whether they are applied
+  create name   create up and down migration files versioned by the current UTC time
+
+flags:
+`
+
+const timestampFormat = "20060102150405"
+
+type options struct {
+	configPath      string
+	migrationsPath  string
+	migrationsTable string
+	dryRun          bool
+}
+
 func main() {
-	var migrationsPath, migrationsTable string
+	var opts options
+
+	flags := flag.NewFlagSet("migrator", flag.ExitOnError)
+	flags.StringVar(&opts.configPath, "config", "", "path to config file")
+	flags.StringVar(&opts.migrationsPath, "migrations-path", "", "path to migrations")
+	flags.StringVar(&opts.migrationsTable, "migrations-table", "migrations", "name of migrations table")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "print pending SQL of up, down and goto instead of running it")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	_ = flags.Parse(os.Args[1:])
+
+	if opts.migrationsPath == "" {
+		panic("migrations path is required")
+	}
+
+	command, args := "up", flags.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	if command == "create" {
+		if len(args) != 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		create(opts.migrationsPath, args[0])
+
+		return
+	}
+
+	switch command {
+	case "up", "down", "goto", "force", "version", "status":
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	m := newMigrate(opts)
+	defer m.Close()
+
+	switch command {
+	case "up":
+		n := optionalArg(flags, args, 0)
+		if opts.dryRun {
+			printPlan(opts, m, planUp(opts, m, n))
+
+			return
+		}
+
+		if n > 0 {
+			steps(m, int(n))
+		} else {
+			run(m.Up())
+		}
+	case "down":
+		n := optionalArg(flags, args, 1)
+		if opts.dryRun {
+			printPlan(opts, m, planDown(opts, m, n))
+
+			return
+		}
+
+		steps(m, -int(n))
+	case "goto":
+		v := requiredArg(flags, args)
+		if opts.dryRun {
+			printPlan(opts, m, planGoto(opts, m, v))
+
+			return
+		}
+
+		run(m.Migrate(v))
+	case "force":
+		if len(args) != 1 {
+			flags.Usage()
+			os.Exit(2)
+		}
+
+		v, err := strconv.Atoi(args[0])
+		if err != nil {
+			panic("invalid version: " + args[0])
+		}
+
+		if err := m.Force(v); err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("version forced to %d\n", v)
+	case "version":
+		version, dirty, ok := currentVersion(m)
+		if !ok {
+			fmt.Println("no migrations applied")
 
-	flag.StringVar(&migrationsPath, "migrations-path", "", "path to migrations")
-	flag.StringVar(&migrationsTable, "migrations-table", "migrations", "name of migrations table")
+			return
+		}
+
+		fmt.Printf("%d%s\n", version, dirtySuffix(dirty))
+	case "status":
+		status(opts, m)
+	}
+}
 
-	cfg := config.MustLoad()
+func newMigrate(opts options) *migrate.Migrate {
+	cfg := config.MustLoadPath(opts.configPath)
 	cfg.DB.Password = os.Getenv("POSTGRES_PASSWORD")
 	if cfg.DB.Password == "" {
 		panic("POSTGRESS_PASSWORD is required")
 	}
 
-	if migrationsPath == "" {
-		panic("migrations path is required")
-	}
-
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s&x-migrations-table=%s",
-		cfg.DB.Username, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.DBName, cfg.DB.SSLMode, migrationsTable)
+		cfg.DB.Username, cfg.DB.Password, cfg.DB.Host, cfg.DB.Port, cfg.DB.DBName, cfg.DB.SSLMode, opts.migrationsTable)
 
 	m, err := migrate.New(
-		"file://"+migrationsPath,
+		"file://"+opts.migrationsPath,
 		dsn,
 	)
 	if err != nil {
 		panic(err)
 	}
 
-	if err := m.Up(); err != nil {
+	return m
+}
+
+func run(err error) {
+	if err != nil {
 		if errors.Is(err, migrate.ErrNoChange) {
 			fmt.Println("no migrations to apply")
 
@@ -51,3 +181,261 @@ func main() {
 
 	fmt.Println("migrations applied successfully")
 }
+
+// steps runs n migrations up, or -n migrations down. Asking for more migrations
+// than are pending or applied fails, though the available ones are still run.
+func steps(m *migrate.Migrate, n int) {
+	err := m.Steps(n)
+
+	requested, state, done := n, "pending", "applied"
+	if n < 0 {
+		requested, state, done = -n, "applied", "rolled back"
+	}
+
+	var short migrate.ErrShortLimit
+	switch {
+	case errors.As(err, &short):
+		fail("only %d of %d migrations were %s, they have been %s", requested-int(short.Short), requested, state, done)
+	case errors.Is(err, os.ErrNotExist):
+		fail("no migrations are %s", state)
+	}
+
+	run(err)
+}
+
+// fail reports the error and exits with a non-zero status.
+func fail(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "migrator: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+func optionalArg(flags *flag.FlagSet, args []string, def uint) uint {
+	if len(args) == 0 {
+		return def
+	}
+
+	return requiredArg(flags, args)
+}
+
+func requiredArg(flags *flag.FlagSet, args []string) uint {
+	if len(args) != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	n, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		panic("invalid number: " + args[0])
+	}
+
+	return uint(n)
+}
+
+func currentVersion(m *migrate.Migrate) (uint, bool, bool) {
+	version, dirty, err := m.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return 0, false, false
+		}
+
+		panic(err)
+	}
+
+	return version, dirty, true
+}
+
+func dirtySuffix(dirty bool) string {
+	if dirty {
+		return " (dirty)"
+	}
+
+	return ""
+}
+
+// create adds empty up and down files of a new migration versioned by the current
+// UTC time, so migrations created on different branches do not collide.
+func create(dir, name string) {
+	version := time.Now().UTC().Format(timestampFormat)
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			panic(err)
+		}
+		f.Close()
+
+		fmt.Println(path)
+	}
+}
+
+// step is a single migration that would be run in the given direction.
+type step struct {
+	version uint
+	up      bool
+}
+
+// sourceVersions returns the versions of all migration files in ascending order.
+func sourceVersions(opts options) []uint {
+	src, err := source.Open("file://" + opts.migrationsPath)
+	if err != nil {
+		panic(err)
+	}
+	defer src.Close()
+
+	var versions []uint
+
+	version, err := src.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		panic(err)
+	}
+
+	return versions
+}
+
+func planUp(opts options, m *migrate.Migrate, n uint) []step {
+	current, _, ok := currentVersion(m)
+
+	var steps []step
+	for _, version := range sourceVersions(opts) {
+		if ok && version <= current {
+			continue
+		}
+		if n > 0 && uint(len(steps)) == n {
+			break
+		}
+
+		steps = append(steps, step{version: version, up: true})
+	}
+
+	return steps
+}
+
+func planDown(opts options, m *migrate.Migrate, n uint) []step {
+	current, _, ok := currentVersion(m)
+	if !ok {
+		return nil
+	}
+
+	versions := sourceVersions(opts)
+
+	var steps []step
+	for i := len(versions) - 1; i >= 0 && uint(len(steps)) < n; i-- {
+		if versions[i] > current {
+			continue
+		}
+
+		steps = append(steps, step{version: versions[i]})
+	}
+
+	return steps
+}
+
+func planGoto(opts options, m *migrate.Migrate, target uint) []step {
+	current, _, ok := currentVersion(m)
+	if !ok || target > current {
+		var steps []step
+		for _, version := range sourceVersions(opts) {
+			if ok && version <= current {
+				continue
+			}
+			if version > target {
+				break
+			}
+
+			steps = append(steps, step{version: version, up: true})
+		}
+
+		return steps
+	}
+
+	versions := sourceVersions(opts)
+
+	var steps []step
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i] > current {
+			continue
+		}
+		if versions[i] <= target {
+			break
+		}
+
+		steps = append(steps, step{version: versions[i]})
+	}
+
+	return steps
+}
+
+func printPlan(opts options, m *migrate.Migrate, steps []step) {
+	if _, dirty, _ := currentVersion(m); dirty {
+		fmt.Println("-- warning: database is dirty, fix it with force before migrating")
+	}
+
+	if len(steps) == 0 {
+		fmt.Println("-- no migrations to apply")
+
+		return
+	}
+
+	src, err := source.Open("file://" + opts.migrationsPath)
+	if err != nil {
+		panic(err)
+	}
+	defer src.Close()
+
+	for _, s := range steps {
+		read, direction := src.ReadDown, "down"
+		if s.up {
+			read, direction = src.ReadUp, "up"
+		}
+
+		body, identifier, err := read(s.version)
+		if err != nil {
+			panic(err)
+		}
+
+		sql, err := io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("-- %d_%s.%s.sql\n%s\n", s.version, identifier, direction, sql)
+	}
+}
+
+func status(opts options, m *migrate.Migrate) {
+	current, dirty, ok := currentVersion(m)
+
+	if ok {
+		fmt.Printf("current version: %d%s\n", current, dirtySuffix(dirty))
+	} else {
+		fmt.Println("current version: none")
+	}
+
+	src, err := source.Open("file://" + opts.migrationsPath)
+	if err != nil {
+		panic(err)
+	}
+	defer src.Close()
+
+	for _, version := range sourceVersions(opts) {
+		state := "pending"
+		if ok && version <= current {
+			state = "applied"
+		}
+
+		identifier := ""
+		if body, id, err := src.ReadUp(version); err == nil {
+			body.Close()
+			identifier = id
+		}
+
+		fmt.Printf("%-8s %d_%s\n", state, version, identifier)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+
+	"github.com/zanzhit/flat-seller/internal/config"
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/storage/postgres"
+	authstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/auth"
+	flatstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/flat"
+	housestorage "github.com/zanzhit/flat-seller/internal/storage/postgres/house"
+)
+
+const generatedPassword = "password"
+
+// Fixtures describes the seed data. Flats refer to their owner and moderator by user key.
+type Fixtures struct {
+	Users  []UserFixture  `yaml:"users" json:"users"`
+	Houses []HouseFixture `yaml:"houses" json:"houses"`
+}
+
+type UserFixture struct {
+	Key      string `yaml:"key" json:"key"`
+	Email    string `yaml:"email" json:"email"`
+	Password string `yaml:"password" json:"password"`
+	UserType string `yaml:"user_type" json:"user_type"`
+}
+
+type HouseFixture struct {
+	Address   string        `yaml:"address" json:"address"`
+	Developer string        `yaml:"developer" json:"developer"`
+	Year      int           `yaml:"year" json:"year"`
+	Flats     []FlatFixture `yaml:"flats" json:"flats"`
+}
+
+type FlatFixture struct {
+	Price          int    `yaml:"price" json:"price"`
+	Rooms          int    `yaml:"rooms" json:"rooms"`
+	Status         string `yaml:"status" json:"status"`
+	Owner          string `yaml:"owner" json:"owner"`
+	Moderator      string `yaml:"moderator" json:"moderator"`
+	DeclineReason  string `yaml:"decline_reason" json:"decline_reason"`
+	DeclineComment string `yaml:"decline_comment" json:"decline_comment"`
+}
+
+type seeder struct {
+	auth  *authstorage.AuthStorage
+	house *housestorage.HouseStorage
+	flat  *flatstorage.FlatStorage
+
+	// users maps fixture keys to the ids of the saved users.
+	users map[string]string
+}
+
+func main() {
+	var configPath, fixturesPath string
+	var houses, flats int
+	var seed int64
+
+	flags := flag.NewFlagSet("seeder", flag.ExitOnError)
+	flags.StringVar(&configPath, "config", "", "path to config file")
+	flags.StringVar(&fixturesPath, "fixtures", "", "path to YAML or JSON fixtures")
+	flags.IntVar(&houses, "houses", 0, "number of houses to generate")
+	flags.IntVar(&flats, "flats", 0, "number of flats to generate in every house")
+	flags.Int64Var(&seed, "seed", time.Now().UnixNano(), "random seed of the generator")
+
+	_ = flags.Parse(os.Args[1:])
+
+	if fixturesPath == "" && houses == 0 {
+		panic("fixtures path or number of houses is required")
+	}
+
+	cfg := config.MustLoadPath(configPath)
+	cfg.DB.Password = os.Getenv("POSTGRES_PASSWORD")
+	if cfg.DB.Password == "" {
+		panic("POSTGRES_PASSWORD is required")
+	}
+
+	storage, err := postgres.New(*cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer storage.Close()
+
+	s := &seeder{
+		auth:  authstorage.New(storage, cfg.DB.QueryTimeout),
+		house: housestorage.New(storage, cfg.DB.QueryTimeout),
+		flat:  flatstorage.New(storage, cfg.DB.QueryTimeout),
+		users: make(map[string]string),
+	}
+
+	ctx := context.Background()
+
+	if fixturesPath != "" {
+		fixtures, err := loadFixtures(fixturesPath)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := s.load(ctx, fixtures); err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("loaded %d users and %d houses from %s\n", len(fixtures.Users), len(fixtures.Houses), fixturesPath)
+	}
+
+	if houses > 0 {
+		fixtures := generate(rand.New(rand.NewSource(seed)), houses, flats)
+
+		if err := s.load(ctx, fixtures); err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("generated %d houses with %d flats each (seed %d)\n", houses, flats, seed)
+	}
+}
+
+func loadFixtures(path string) (Fixtures, error) {
+	const op = "seeder.loadFixtures"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var fixtures Fixtures
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, &fixtures)
+	} else {
+		err = yaml.Unmarshal(data, &fixtures)
+	}
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fixtures, nil
+}
+
+func (s *seeder) load(ctx context.Context, fixtures Fixtures) error {
+	const op = "seeder.load"
+
+	for _, user := range fixtures.Users {
+		if err := s.saveUser(ctx, user); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	for _, house := range fixtures.Houses {
+		saved, err := s.house.SaveHouse(ctx, house.Address, house.Developer, house.Year)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		for _, flat := range house.Flats {
+			if err := s.saveFlat(ctx, saved.ID, flat); err != nil {
+				return fmt.Errorf("%s: house %d: %w", op, saved.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *seeder) saveUser(ctx context.Context, user UserFixture) error {
+	const op = "seeder.saveUser"
+
+	if user.UserType != constants.User && user.UserType != constants.Admin {
+		return fmt.Errorf("%s: user %q: unknown user type %q", op, user.Key, user.UserType)
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	id, err := s.auth.SaveUser(ctx, user.Email, user.UserType, passHash)
+	if err != nil {
+		return fmt.Errorf("%s: user %q: %w", op, user.Key, err)
+	}
+
+	if user.Key != "" {
+		s.users[user.Key] = id
+	}
+
+	return nil
+}
+
+// saveFlat creates the flat and moves it to the fixture status the same way
+// the moderator or the owner would.
+func (s *seeder) saveFlat(ctx context.Context, houseID int, fixture FlatFixture) error {
+	const op = "seeder.saveFlat"
+
+	ownerID, err := s.userID(fixture.Owner)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	flat, err := s.flat.SaveFlat(ctx, ownerID, houseID, fixture.Price, fixture.Rooms)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	update := flat
+	update.Status = fixture.Status
+
+	switch fixture.Status {
+	case "", constants.Created:
+		return nil
+	case constants.Withdrawn:
+		if _, err := s.flat.UpdateOwnerFlat(ctx, update, ownerID, constants.Created); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	case constants.Moderation:
+		now := time.Now()
+		update.ModerationStartedAt = &now
+	case constants.Declined:
+		reason := fixture.DeclineReason
+		if reason == "" {
+			reason = constants.ReasonIncorrectData
+		}
+		update.DeclineReason = &reason
+		if fixture.DeclineComment != "" {
+			update.DeclineComment = &fixture.DeclineComment
+		}
+	case constants.Approved:
+	default:
+		return fmt.Errorf("%s: unknown status %q", op, fixture.Status)
+	}
+
+	moderatorID, err := s.userID(fixture.Moderator)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if fixture.Status == constants.Moderation {
+		update.ModeratorID = &moderatorID
+	}
+
+	if _, err := s.flat.UpdateFlat(ctx, update, moderatorID, constants.Created, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *seeder) userID(key string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+
+	id, ok := s.users[key]
+	if !ok {
+		return "", fmt.Errorf("unknown user %q", key)
+	}
+
+	return id, nil
+}
+
+var (
+	streets    = []string{"Lenina", "Pushkina", "Gagarina", "Mira", "Sadovaya", "Tverskaya"}
+	developers = []string{"PIK", "Samolet", "LSR", "Etalon", "Donstroy"}
+	statuses   = []string{constants.Created, constants.Approved, constants.Approved, constants.Declined, constants.Moderation}
+	reasons    = []string{constants.ReasonIncorrectPrice, constants.ReasonIncorrectData, constants.ReasonDuplicate}
+)
+
+// generate builds houses × flats synthetic fixtures with a client owning every flat
+// and a moderator reviewing them. Every run gets its own emails, so runs can be repeated.
+func generate(rnd *rand.Rand, houses, flats int) Fixtures {
+	run := rnd.Int63()
+
+	fixtures := Fixtures{
+		Users: []UserFixture{
+			{Key: "client", Email: fmt.Sprintf("client-%d@example.com", run), Password: generatedPassword, UserType: constants.User},
+			{Key: "moderator", Email: fmt.Sprintf("moderator-%d@example.com", run), Password: generatedPassword, UserType: constants.Admin},
+		},
+		Houses: make([]HouseFixture, houses),
+	}
+
+	for i := range fixtures.Houses {
+		house := HouseFixture{
+			Address:   fmt.Sprintf("%s st. %d", streets[rnd.Intn(len(streets))], rnd.Intn(200)+1),
+			Developer: developers[rnd.Intn(len(developers))],
+			Year:      1960 + rnd.Intn(66),
+			Flats:     make([]FlatFixture, flats),
+		}
+
+		for j := range house.Flats {
+			flat := FlatFixture{
+				Price:     (rnd.Intn(300) + 20) * 100000,
+				Rooms:     rnd.Intn(5) + 1,
+				Status:    statuses[rnd.Intn(len(statuses))],
+				Owner:     "client",
+				Moderator: "moderator",
+			}
+			if flat.Status == constants.Declined {
+				flat.DeclineReason = reasons[rnd.Intn(len(reasons))]
+			}
+
+			house.Flats[j] = flat
+		}
+
+		fixtures.Houses[i] = house
+	}
+
+	return fixtures
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/zanzhit/flat-seller/api"
+	"github.com/zanzhit/flat-seller/internal/config"
+	authhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/auth"
+	commenthandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/comment"
+	flathandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/flat"
+	healthhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/health"
+	househandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/house"
+	moderationhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/moderation"
+	subscriptionhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/subscription"
+	authmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/auth"
+	"github.com/zanzhit/flat-seller/internal/http-server/middleware/logger"
+	metricsmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/metrics"
+	openapimid "github.com/zanzhit/flat-seller/internal/http-server/middleware/openapi"
+	tracingmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/tracing"
+	"github.com/zanzhit/flat-seller/internal/lib/metrics"
+	"github.com/zanzhit/flat-seller/internal/lib/migrations"
+	"github.com/zanzhit/flat-seller/internal/lib/sender"
+	"github.com/zanzhit/flat-seller/internal/lib/tracing"
+	authservice "github.com/zanzhit/flat-seller/internal/services/auth"
+	flatservice "github.com/zanzhit/flat-seller/internal/services/flat"
+	"github.com/zanzhit/flat-seller/internal/services/notifier"
+	"github.com/zanzhit/flat-seller/internal/storage/cache"
+	"github.com/zanzhit/flat-seller/internal/storage/cache/memory"
+	authstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/auth"
+	commentstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/comment"
+	flatstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/flat"
+	healthstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/health"
+	housestorage "github.com/zanzhit/flat-seller/internal/storage/postgres/house"
+	subscriptionstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/subscription"
+	tokenstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/token"
+)
+
+// App is the HTTP server with all its dependencies wired together. The database
+// is owned by the caller, so the same app can run against any schema.
+type App struct {
+	log *slog.Logger
+	cfg *config.Config
+
+	router          chi.Router
+	srv             *http.Server
+	healthHandler   *healthhandler.HealthHandler
+	flatNotifier    *notifier.Notifier
+	emailSender     *sender.Sink
+	shutdownTracing func(context.Context) error
+}
+
+func New(log *slog.Logger, cfg *config.Config, storage *sqlx.DB) (_ *App, err error) {
+	const op = "app.New"
+
+	// cleanups release what has been set up so far, in reverse order, if New fails.
+	var cleanups []func() error
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			if cleanupErr := cleanups[i](); cleanupErr != nil {
+				err = errors.Join(err, fmt.Errorf("%s: cleanup: %w", op, cleanupErr))
+			}
+		}
+	}()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	cleanups = append(cleanups, func() error {
+		return shutdownTracing(context.Background())
+	})
+
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB(storage.DB, "postgres")
+
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID)
+	router.Use(tracingmid.New())
+	router.Use(logger.New(log))
+	router.Use(metricsmid.New(appMetrics))
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.URLFormat)
+
+	apiDoc, err := openapimid.Load(context.Background(), api.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cfg.OpenAPI.ValidateRequests {
+		validator, err := openapimid.New(log, apiDoc, cfg.OpenAPI.ValidateResponses)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		router.Use(validator)
+	}
+
+	authstorage := authstorage.New(storage, cfg.DB.QueryTimeout)
+	tokenStorage := tokenstorage.New(storage, cfg.DB.QueryTimeout)
+	authService := authservice.New(log, authstorage, authstorage, tokenStorage, appMetrics, cfg.TokenTTL, cfg.RefreshTokenTTL, cfg.Secret)
+	authhandler := authhandler.New(log, authService)
+
+	var houseStorage househandler.House = housestorage.New(storage, cfg.DB.QueryTimeout)
+	var flatStorage flatservice.Flat = flatstorage.New(storage, cfg.DB.QueryTimeout)
+	if cfg.Cache.Enabled {
+		listings := cache.NewListings(memory.New(cfg.Cache.MaxEntries))
+		houseStorage = cache.NewHouse(log, houseStorage, listings, cfg.Cache.TTL)
+		flatStorage = cache.NewFlat(flatStorage, listings)
+	}
+
+	houseHandler := househandler.New(log, houseStorage)
+
+	subscriptionStorage := subscriptionstorage.New(storage, cfg.DB.QueryTimeout)
+	subscriptionHandler := subscriptionhandler.New(log, subscriptionStorage)
+
+	emailSender, err := sender.New(log, cfg.Notifier.SinkPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	cleanups = append(cleanups, emailSender.Close)
+
+	flatNotifier := notifier.New(log, emailSender, subscriptionStorage, cfg.Notifier)
+
+	flatService := flatservice.New(log, flatStorage, flatNotifier, appMetrics, cfg.ModerationTTL)
+	flatHandler := flathandler.New(log, flatService)
+	moderationHandler := moderationhandler.New(log, flatService)
+
+	commentStorage := commentstorage.New(storage, cfg.DB.QueryTimeout)
+	commentHandler := commenthandler.New(log, commentStorage, flatService)
+
+	var latestMigration uint
+	if cfg.Health.MigrationsPath != "" {
+		latestMigration, err = migrations.LatestVersion(cfg.Health.MigrationsPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	healthStorage := healthstorage.New(storage, cfg.DB.QueryTimeout, cfg.Health.MigrationsTable)
+	healthHandler := healthhandler.New(log, healthStorage, latestMigration)
+
+	router.Get("/healthz", healthHandler.Liveness)
+	router.Get("/readyz", healthHandler.Readiness)
+	router.Method(http.MethodGet, "/metrics", appMetrics.Handler())
+
+	router.Post("/register", authhandler.RegisterNewUser)
+	router.Post("/login", authhandler.Login)
+	router.Post("/dummyLogin", authhandler.DummyLogin)
+	router.Post("/token/refresh", authhandler.Refresh)
+
+	router.With(authmid.JWTAuth(cfg.Secret, tokenStorage)).Group(func(r chi.Router) {
+		r.Post("/logout", authhandler.Logout)
+		r.With(authmid.AdminRequired).Post("/users/{id}/revoke", authhandler.RevokeUser)
+		r.Post("/flat/create", flatHandler.SaveFlat)
+		r.Get("/me/flats", flatHandler.OwnerFlats)
+		r.Patch("/me/flats/{id}", flatHandler.EditFlat)
+		r.Post("/me/flats/{id}/withdraw", flatHandler.WithdrawFlat)
+		r.With(authmid.AdminRequired).Post("/flat/update", flatHandler.UpdateFlat)
+		r.With(authmid.AdminRequired).Post("/house/create", houseHandler.SaveHouse)
+		r.Get("/houses", houseHandler.Houses)
+		r.Get("/house/{id}", houseHandler.House)
+		r.With(authmid.AdminRequired).Patch("/house/{id}", houseHandler.UpdateHouse)
+		r.With(authmid.AdminRequired).Delete("/house/{id}", houseHandler.DeleteHouse)
+		r.Get("/flat/{id}", flatHandler.Flat)
+		r.Get("/flats/search", houseHandler.Search)
+		r.Post("/house/{id}/subscribe", subscriptionHandler.Subscribe)
+		r.With(authmid.AdminRequired).Get("/moderation/queue", moderationHandler.Queue)
+		r.With(authmid.AdminRequired).Post("/moderation/queue/take", moderationHandler.TakeNext)
+		r.Get("/flat/{id}/comments", commentHandler.Comments)
+		r.With(authmid.AdminRequired).Post("/flat/{id}/comments", commentHandler.SaveComment)
+	})
+
+	flatNotifier.Start()
+
+	return &App{
+		log: log,
+		cfg: cfg,
+		srv: &http.Server{
+			Addr:         cfg.Address,
+			Handler:      router,
+			ReadTimeout:  cfg.Timeout,
+			WriteTimeout: cfg.Timeout,
+			IdleTimeout:  cfg.IdleTimeout,
+		},
+		router:          router,
+		healthHandler:   healthHandler,
+		flatNotifier:    flatNotifier,
+		emailSender:     emailSender,
+		shutdownTracing: shutdownTracing,
+	}, nil
+}
+
+// Handler returns the router, so the app can be served by httptest.Server.
+func (a *App) Handler() http.Handler {
+	return a.router
+}
+
+// Run serves HTTP on the configured address until Stop is called.
+func (a *App) Run() error {
+	const op = "app.Run"
+
+	a.log.Info("starting http server", slog.String("address", a.cfg.Address))
+
+	if err := a.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Stop fails the readiness probe, waits for the drain delay and then shuts the
+// server and background workers down. Everything is stopped even if a step fails;
+// the failures are returned together.
+func (a *App) Stop(ctx context.Context) error {
+	const op = "app.Stop"
+
+	a.healthHandler.Drain()
+	time.Sleep(a.cfg.Health.DrainDelay)
+
+	var failed []error
+
+	if err := a.srv.Shutdown(ctx); err != nil {
+		failed = append(failed, fmt.Errorf("shut down server: %w", err))
+	}
+
+	if err := a.flatNotifier.Stop(ctx); err != nil {
+		failed = append(failed, fmt.Errorf("deliver pending notifications: %w", err))
+	}
+
+	if err := a.emailSender.Close(); err != nil {
+		failed = append(failed, fmt.Errorf("close email sender: %w", err))
+	}
+
+	if err := a.shutdownTracing(ctx); err != nil {
+		failed = append(failed, fmt.Errorf("stop tracing: %w", err))
+	}
+
+	if err := errors.Join(failed...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	authhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/auth"
+	commenthandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/comment"
+	flathandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/flat"
+	househandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/house"
+	"github.com/zanzhit/flat-seller/internal/storage/postgres/postgrestest"
+)
+
+// client calls the server on behalf of one user.
+type client struct {
+	t      *testing.T
+	server *httptest.Server
+	id     string
+	token  string
+}
+
+// do sends the request with body encoded as JSON, checks the status and decodes
+// the response into out, if it is not nil.
+func (c *client) do(method, path string, body any, status int, out any) {
+	c.t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+
+	r, err := http.NewRequest(method, c.server.URL+path, &payload)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.server.Client().Do(r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != status {
+		var problem bytes.Buffer
+		_, _ = problem.ReadFrom(res.Body)
+		c.t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, status, res.StatusCode, problem.String())
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			c.t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
+
+// signUp registers a user of the type and logs them in.
+func signUp(t *testing.T, server *httptest.Server, email, userType string) *client {
+	t.Helper()
+
+	c := &client{t: t, server: server}
+
+	var registered struct {
+		ID string `json:"id"`
+	}
+	c.do(http.MethodPost, "/register", authhandler.RequestRegister{
+		Email:    email,
+		Password: "Secret123",
+		UserType: userType,
+	}, http.StatusOK, &registered)
+	c.id = registered.ID
+
+	c.logIn(email)
+
+	return c
+}
+
+// logIn replaces the token of the client with a fresh one.
+func (c *client) logIn(email string) {
+	c.t.Helper()
+
+	var tokens models.Tokens
+	c.do(http.MethodPost, "/login", authhandler.RequestLogin{Email: email, Password: "Secret123"}, http.StatusOK, &tokens)
+	c.token = tokens.AccessToken
+}
+
+func TestModerationFlow(t *testing.T) {
+	a := newTestApp(t, postgrestest.New(t))
+
+	server := httptest.NewServer(a.Handler())
+	t.Cleanup(server.Close)
+
+	seller := signUp(t, server, "seller@example.com", constants.User)
+	buyer := signUp(t, server, "buyer@example.com", constants.User)
+	moderator := signUp(t, server, "moderator@example.com", constants.Admin)
+
+	var house models.House
+	moderator.do(http.MethodPost, "/house/create", househandler.Request{
+		Address:   "Лесная улица, 7",
+		Year:      2000,
+		Developer: "Мэрия",
+	}, http.StatusOK, &house)
+
+	seller.do(http.MethodPost, "/house/create", househandler.Request{Address: "Лесная улица, 9", Year: 2000},
+		http.StatusForbidden, nil)
+
+	var approved, waiting models.Flat
+	seller.do(http.MethodPost, "/flat/create", flathandler.SaveRequest{
+		Id:      house.ID,
+		Request: flathandler.Request{Price: 5000000, Rooms: 2},
+	}, http.StatusOK, &approved)
+	seller.do(http.MethodPost, "/flat/create", flathandler.SaveRequest{
+		Id:      house.ID,
+		Request: flathandler.Request{Price: 7000000, Rooms: 3},
+	}, http.StatusOK, &waiting)
+
+	if approved.FlatNumber != 1 || waiting.FlatNumber != 2 || approved.Status != constants.Created {
+		t.Fatalf("unexpected flats: %+v, %+v", approved, waiting)
+	}
+
+	var taken models.Flat
+	moderator.do(http.MethodPost, "/moderation/queue/take", nil, http.StatusOK, &taken)
+	if taken.ID != approved.ID || taken.Status != constants.Moderation {
+		t.Fatalf("expected the oldest flat on moderation, got %+v", taken)
+	}
+
+	var updated models.Flat
+	// An approval carries no decline reason, which UpdateRequest would send empty.
+	moderator.do(http.MethodPost, "/flat/update", map[string]any{
+		"id":     taken.ID,
+		"price":  taken.Price,
+		"rooms":  taken.Rooms,
+		"status": constants.Approved,
+	}, http.StatusOK, &updated)
+	if updated.Status != constants.Approved {
+		t.Fatalf("expected the flat to be approved, got %+v", updated)
+	}
+
+	housePath := fmt.Sprintf("/house/%d", house.ID)
+
+	assertHouseFlats(t, buyer, housePath, approved.ID)
+	assertHouseFlats(t, seller, housePath, approved.ID)
+	assertHouseFlats(t, moderator, housePath, approved.ID, waiting.ID)
+
+	buyer.do(http.MethodGet, fmt.Sprintf("/flat/%d", approved.ID), nil, http.StatusOK, nil)
+	buyer.do(http.MethodGet, fmt.Sprintf("/flat/%d", waiting.ID), nil, http.StatusNotFound, nil)
+	seller.do(http.MethodGet, fmt.Sprintf("/flat/%d", waiting.ID), nil, http.StatusOK, nil)
+	moderator.do(http.MethodGet, fmt.Sprintf("/flat/%d", waiting.ID), nil, http.StatusOK, nil)
+
+	var own []models.Flat
+	seller.do(http.MethodGet, "/me/flats", nil, http.StatusOK, &own)
+	if len(own) != 2 {
+		t.Fatalf("expected both flats of the seller, got %+v", own)
+	}
+
+	moderator.do(http.MethodPost, "/users/"+seller.id+"/revoke", nil, http.StatusNoContent, nil)
+	seller.do(http.MethodGet, "/me/flats", nil, http.StatusUnauthorized, nil)
+
+	seller.logIn("Seller@Example.com")
+	seller.do(http.MethodGet, "/me/flats", nil, http.StatusOK, nil)
+
+	commentsPath := fmt.Sprintf("/flat/%d/comments", approved.ID)
+	moderator.do(http.MethodPost, commentsPath, commenthandler.Request{Text: "Checked the documents"}, http.StatusOK, nil)
+
+	moderator.do(http.MethodGet, fmt.Sprintf("/flat/%d/comments", waiting.ID+100), nil, http.StatusNotFound, nil)
+
+	moderator.do(http.MethodDelete, housePath, nil, http.StatusNoContent, nil)
+	moderator.do(http.MethodPost, commentsPath, commenthandler.Request{Text: "Too late"}, http.StatusNotFound, nil)
+	moderator.do(http.MethodGet, commentsPath, nil, http.StatusNotFound, nil)
+}
+
+func assertHouseFlats(t *testing.T, c *client, path string, want ...int) {
+	t.Helper()
+
+	var flats []models.Flat
+	c.do(http.MethodGet, path, nil, http.StatusOK, &flats)
+
+	if len(flats) != len(want) {
+		t.Fatalf("expected flats %v, got %+v", want, flats)
+	}
+	for i, flat := range flats {
+		if flat.ID != want[i] {
+			t.Fatalf("expected flats %v, got %+v", want, flats)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+
+	"github.com/zanzhit/flat-seller/api"
+	"github.com/zanzhit/flat-seller/internal/config"
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	authhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/auth"
+	commenthandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/comment"
+	flathandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/flat"
+	healthhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/health"
+	househandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/house"
+	subscriptionhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/subscription"
+	openapimid "github.com/zanzhit/flat-seller/internal/http-server/middleware/openapi"
+	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
+)
+
+// sample is a request to an operation and a response the server may answer it with.
+// Bodies are built from the types the handlers decode and render, so a field renamed
+// on either side breaks the test.
+type sample struct {
+	method   string
+	path     string
+	body     any
+	status   int
+	response any
+}
+
+const (
+	userID  = "cae36e0f-69e5-4fa8-a179-a52d083c5549"
+	otherID = "0b5d3c61-1f0e-4e7c-9d5e-6f2a1c3b4d5e"
+)
+
+func samples() []sample {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	reason := constants.ReasonIncorrectPrice
+	comment := "too cheap"
+	minPrice, maxPrice := 1000, 5000
+	moderator := models.User{Id: otherID, UserType: constants.Admin}
+
+	house := models.House{ID: 1, Address: "Лесная улица, 7", Year: 2000, Developer: "Мэрия", CreatedAt: now, UpdatedAt: now}
+	flat := handlers.FlatView(models.Flat{
+		ID:                  1,
+		HouseID:             1,
+		Price:               1000,
+		Rooms:               2,
+		FlatNumber:          1,
+		Status:              constants.Declined,
+		CreatedBy:           ptr(userID),
+		ModeratorID:         ptr(otherID),
+		ModerationStartedAt: &now,
+		DeclineReason:       &reason,
+		DeclineComment:      &comment,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}, moderator)
+	flats := []handlers.Flat{flat}
+	withdrawn := handlers.FlatView(models.Flat{
+		ID:         1,
+		HouseID:    1,
+		Price:      1000,
+		Rooms:      2,
+		FlatNumber: 1,
+		Status:     constants.Withdrawn,
+		CreatedBy:  ptr(userID),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}, models.User{Id: userID, UserType: constants.User})
+	health := healthhandler.New(discard, healthCheck{version: 10}, 10)
+	unhealthy := healthhandler.New(discard, healthCheck{err: errors.New("connection refused"), version: 9}, 10)
+	tokens := models.Tokens{AccessToken: "access", RefreshToken: "refresh"}
+	flatRequest := flathandler.Request{Price: 1000, Rooms: 2}
+
+	return []sample{
+		{method: http.MethodGet, path: "/healthz", status: http.StatusOK, response: probe(health.Liveness, "/healthz")},
+		{method: http.MethodGet, path: "/readyz", status: http.StatusOK, response: probe(health.Readiness, "/readyz")},
+		{method: http.MethodGet, path: "/readyz", status: http.StatusServiceUnavailable,
+			response: probe(unhealthy.Readiness, "/readyz")},
+		{method: http.MethodGet, path: "/metrics", status: http.StatusOK, response: "# HELP up\n"},
+		{method: http.MethodPost, path: "/dummyLogin", body: map[string]string{"user_type": constants.User},
+			status: http.StatusOK, response: map[string]string{"token": "access"}},
+		{method: http.MethodPost, path: "/register", body: authhandler.RequestRegister{
+			Email:    "test@gmail.com",
+			Password: "Secret123",
+			UserType: constants.User,
+		}, status: http.StatusOK, response: map[string]string{"id": userID}},
+		{method: http.MethodPost, path: "/login", body: authhandler.RequestLogin{Email: "test@gmail.com", Password: "Secret123"},
+			status: http.StatusOK, response: tokens},
+		{method: http.MethodPost, path: "/token/refresh", body: authhandler.RequestRefresh{RefreshToken: "refresh"},
+			status: http.StatusOK, response: tokens},
+		{method: http.MethodPost, path: "/logout", status: http.StatusOK},
+		{method: http.MethodPost, path: "/users/" + userID + "/revoke", status: http.StatusNoContent},
+		{method: http.MethodPost, path: "/house/create", body: househandler.Request{Address: house.Address, Year: house.Year},
+			status: http.StatusOK, response: house},
+		{method: http.MethodGet, path: "/houses?limit=10", status: http.StatusOK, response: []models.HouseSummary{
+			{House: house, FlatsCount: 2, MinPrice: &minPrice, MaxPrice: &maxPrice},
+		}},
+		{method: http.MethodGet, path: "/house/1?sort_by=price&order=desc", status: http.StatusOK, response: flats},
+		{method: http.MethodPatch, path: "/house/1", body: househandler.UpdateRequest{Year: ptr(2001)},
+			status: http.StatusOK, response: house},
+		{method: http.MethodDelete, path: "/house/1?hard=true", status: http.StatusNoContent},
+		{method: http.MethodPost, path: "/house/1/subscribe", body: subscriptionhandler.Request{Email: "test@gmail.com"},
+			status: http.StatusOK},
+		{method: http.MethodGet, path: "/flats/search?address=Лесная", status: http.StatusOK, response: flats},
+		{method: http.MethodPost, path: "/flat/create", body: flathandler.SaveRequest{Id: 1, Request: flatRequest},
+			status: http.StatusOK, response: flat},
+		{method: http.MethodPost, path: "/flat/update", body: flathandler.UpdateRequest{
+			Id:            1,
+			Request:       flathandler.Request{Price: 1000, Rooms: 2, Status: constants.Declined},
+			DeclineReason: models.DeclineReason{Code: reason, Comment: comment},
+		}, status: http.StatusOK, response: flat},
+		{method: http.MethodGet, path: "/flat/1", status: http.StatusOK, response: flat},
+		{method: http.MethodGet, path: "/flat/1", status: http.StatusNotFound, response: problem(http.StatusNotFound, "/flat/1")},
+		{method: http.MethodGet, path: "/flat/1/comments", status: http.StatusOK, response: []models.Comment{
+			{ID: 1, FlatID: 1, AuthorID: otherID, Text: comment, CreatedAt: now},
+		}},
+		{method: http.MethodPost, path: "/flat/1/comments", body: commenthandler.Request{Text: comment},
+			status: http.StatusOK, response: models.Comment{ID: 1, FlatID: 1, AuthorID: otherID, Text: comment, CreatedAt: now}},
+		{method: http.MethodGet, path: "/me/flats", status: http.StatusOK, response: flats},
+		{method: http.MethodPatch, path: "/me/flats/1", body: flathandler.EditRequest{Price: 2000, Rooms: 3},
+			status: http.StatusOK, response: flat},
+		{method: http.MethodPost, path: "/me/flats/1/withdraw", status: http.StatusOK, response: withdrawn},
+		{method: http.MethodPost, path: "/me/flats/1/withdraw", status: http.StatusInternalServerError,
+			response: problem(http.StatusInternalServerError, "/me/flats/1/withdraw")},
+		{method: http.MethodGet, path: "/moderation/queue?limit=5", status: http.StatusOK, response: flats},
+		{method: http.MethodPost, path: "/moderation/queue/take", status: http.StatusOK, response: flat},
+	}
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// healthCheck answers the readiness checks of the health handler.
+type healthCheck struct {
+	err     error
+	version uint
+}
+
+func (h healthCheck) Ping(context.Context) error {
+	return h.err
+}
+
+func (h healthCheck) MigrationVersion(context.Context) (uint, bool, error) {
+	return h.version, false, nil
+}
+
+// probe records the response of a health handler.
+func probe(handler http.HandlerFunc, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	return rec
+}
+
+// problem renders the problem the way the handlers do.
+func problem(status int, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handlers.Error(rec, httptest.NewRequest(http.MethodGet, path, nil), status, resp.Error("failed"))
+
+	return rec
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	a := newTestApp(t, unreachableDB(t))
+
+	doc, err := openapimid.Load(context.Background(), api.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := openapimid.CheckRoutes(doc, a.router); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSamplesMatchSpec(t *testing.T) {
+	ctx := context.Background()
+
+	doc, err := openapimid.Load(ctx, api.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// succeeded holds the operations with a sample of a success response; error
+	// samples alone would leave the documented result unchecked.
+	succeeded := make(map[string]bool)
+
+	for _, s := range samples() {
+		t.Run(s.method+" "+s.path, func(t *testing.T) {
+			var body []byte
+			if s.body != nil {
+				if body, err = json.Marshal(s.body); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			r := httptest.NewRequest(s.method, s.path, bytes.NewReader(body))
+			r.Header.Set("Authorization", "Bearer token")
+			if s.body != nil {
+				r.Header.Set("Content-Type", "application/json")
+			}
+
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.status < http.StatusMultipleChoices {
+				succeeded[s.method+" "+route.Path] = true
+			}
+
+			requestInput := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			if err := openapi3filter.ValidateRequest(ctx, requestInput); err != nil {
+				t.Fatalf("request: %v", err)
+			}
+
+			if rec, ok := s.response.(*httptest.ResponseRecorder); ok && rec.Code != s.status {
+				t.Fatalf("sample status %d, the handler answered %d", s.status, rec.Code)
+			}
+
+			header, responseBody := renderSample(t, s.response)
+
+			responseInput := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: requestInput,
+				Status:                 s.status,
+				Header:                 header,
+				Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+			}
+			responseInput.SetBodyBytes(responseBody)
+
+			if err := openapi3filter.ValidateResponse(ctx, responseInput); err != nil {
+				t.Fatalf("response: %v", err)
+			}
+		})
+	}
+
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !succeeded[method+" "+path] {
+				t.Errorf("operation %s %s has no sample of a success response", method, path)
+			}
+		}
+	}
+}
+
+func renderSample(t *testing.T, response any) (http.Header, []byte) {
+	t.Helper()
+
+	header := http.Header{}
+
+	switch response := response.(type) {
+	case nil:
+		return header, nil
+	case *httptest.ResponseRecorder:
+		return response.Header(), response.Body.Bytes()
+	case string:
+		header.Set("Content-Type", "text/plain")
+
+		return header, []byte(response)
+	default:
+		body, err := json.Marshal(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header.Set("Content-Type", "application/json")
+
+		return header, body
+	}
+}
+
+// newTestApp builds the app on the database with a config fit for tests.
+func newTestApp(t *testing.T, db *sqlx.DB) *App {
+	t.Helper()
+
+	cfg := &config.Config{
+		Secret:          "test-secret",
+		TokenTTL:        time.Minute,
+		RefreshTokenTTL: time.Hour,
+		ModerationTTL:   time.Minute,
+		DB:              config.DB{QueryTimeout: 5 * time.Second},
+		Notifier: config.Notifier{
+			Workers:   1,
+			QueueSize: 10,
+			SinkPath:  t.TempDir() + "/emails.log",
+		},
+		OpenAPI: config.OpenAPI{ValidateRequests: true, ValidateResponses: true},
+	}
+
+	a, err := New(discard, cfg, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = a.Stop(ctx)
+	})
+
+	return a
+}
+
+// unreachableDB returns a handle that never connects, for tests that only need the router.
+func unreachableDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Open("postgres", "postgres://test@127.0.0.1:1/test?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	authhandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/auth"
+)
+
+// TestRequestSpans checks that a request is traced from the router through the
+// service down to the SQL query. The database is unreachable, but the query span
+// is started before the connection fails.
+func TestRequestSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	// The global provider delegates only to the first provider set, so it is not
+	// restored; after shutdown it records nothing.
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+
+	a := newTestApp(t, unreachableDB(t))
+
+	body, err := json.Marshal(authhandler.RequestRegister{
+		Email:    "seller@example.com",
+		Password: "Secret123",
+		UserType: constants.User,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/register", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	a.Handler().ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	chain := []struct {
+		name string
+		kind trace.SpanKind
+	}{
+		{"POST /register", trace.SpanKindServer},
+		{"service.auth.Register", trace.SpanKindInternal},
+		{"storage.postgres.auth.SaveUser", trace.SpanKindClient},
+	}
+
+	for i, link := range chain {
+		span, ok := spans[link.name]
+		if !ok {
+			t.Fatalf("span %q was not recorded, got %v", link.name, names(exporter.GetSpans()))
+		}
+		if span.SpanKind != link.kind {
+			t.Errorf("span %q: expected kind %v, got %v", link.name, link.kind, span.SpanKind)
+		}
+
+		if i == 0 {
+			if span.Parent.IsValid() {
+				t.Errorf("span %q: expected a root span, got parent %s", link.name, span.Parent.SpanID())
+			}
+
+			continue
+		}
+
+		parent := spans[chain[i-1].name]
+		if span.Parent.SpanID() != parent.SpanContext.SpanID() || span.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+			t.Errorf("span %q: expected parent %q", link.name, parent.Name)
+		}
+	}
+}
+
+func names(spans tracetest.SpanStubs) []string {
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+
+	return names
+}
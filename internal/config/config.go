@@ -9,11 +9,18 @@ import (
 )
 
 type Config struct {
-	Env        string        `yaml:"env" env-default:"local"`
-	TokenTTL   time.Duration `yaml:"token_ttl" env-default:"24h"`
-	Secret     string        `yaml:"secret" env-required:"true"`
-	HTTPServer `yaml:"http_server"`
-	DB         DB `yaml:"db"`
+	Env             string        `yaml:"env" env-default:"local"`
+	TokenTTL        time.Duration `yaml:"token_ttl" env-default:"15m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" env-default:"720h"`
+	Secret          string        `yaml:"secret" env-required:"true"`
+	ModerationTTL   time.Duration `yaml:"moderation_ttl" env-default:"30m"`
+	HTTPServer      `yaml:"http_server"`
+	DB              DB       `yaml:"db"`
+	Notifier        Notifier `yaml:"notifier"`
+	Cache           Cache    `yaml:"cache"`
+	Tracing         Tracing  `yaml:"tracing"`
+	Health          Health   `yaml:"health"`
+	OpenAPI         OpenAPI  `yaml:"openapi"`
 }
 
 type DB struct {
@@ -23,6 +30,8 @@ type DB struct {
 	Password string `yaml:"password"`
 	DBName   string `yaml:"dbname" env-required:"true"`
 	SSLMode  string `yaml:"sslmode" env-required:"true"`
+
+	QueryTimeout time.Duration `yaml:"query_timeout" env-default:"3s"`
 }
 
 type HTTPServer struct {
@@ -31,8 +40,50 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 }
 
+type Notifier struct {
+	Workers    int           `yaml:"workers" env-default:"2"`
+	QueueSize  int           `yaml:"queue_size" env-default:"100"`
+	MaxRetries int           `yaml:"max_retries" env-default:"3"`
+	RetryDelay time.Duration `yaml:"retry_delay" env-default:"1s"`
+	SinkPath   string        `yaml:"sink_path"`
+}
+
+type Cache struct {
+	Enabled    bool          `yaml:"enabled" env-default:"true"`
+	TTL        time.Duration `yaml:"ttl" env-default:"1m"`
+	MaxEntries int           `yaml:"max_entries" env-default:"10000"`
+}
+
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env-default:"none"`
+	Endpoint    string  `yaml:"endpoint" env-default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure" env-default:"true"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+type Health struct {
+	MigrationsPath  string        `yaml:"migrations_path"`
+	MigrationsTable string        `yaml:"migrations_table" env-default:"migrations"`
+	DrainDelay      time.Duration `yaml:"drain_delay" env-default:"5s"`
+}
+
+// OpenAPI controls validation against the api/api.yaml document.
+type OpenAPI struct {
+	ValidateRequests  bool `yaml:"validate_requests" env-default:"true"`
+	ValidateResponses bool `yaml:"validate_responses" env-default:"false"`
+}
+
+// MustLoad reads the config from the path in the --config flag or CONFIG_PATH.
+// It parses the command line, so commands with their own flags should use MustLoadPath.
 func MustLoad() *Config {
-	configPath := fetchConfigPath()
+	return MustLoadPath(fetchConfigPath())
+}
+
+// MustLoadPath reads the config from the given path, falling back to CONFIG_PATH.
+func MustLoadPath(configPath string) *Config {
+	if configPath == "" {
+		configPath = os.Getenv("CONFIG_PATH")
+	}
 	if configPath == "" {
 		panic("CONFIG_PATH is required")
 	}
@@ -56,9 +107,5 @@ func fetchConfigPath() string {
 	flag.StringVar(&res, "config", "", "path to config file")
 	flag.Parse()
 
-	if res == "" {
-		res = os.Getenv("CONFIG_PATH")
-	}
-
 	return res
 }
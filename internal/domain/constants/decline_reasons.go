@@ -0,0 +1,9 @@
+package constants
+
+const (
+	ReasonIncorrectPrice    = "incorrect_price"
+	ReasonIncorrectData     = "incorrect_data"
+	ReasonDuplicate         = "duplicate"
+	ReasonProhibitedContent = "prohibited_content"
+	ReasonOther             = "other"
+)
@@ -5,4 +5,5 @@ const (
 	Approved   = "approved"
 	Declined   = "declined"
 	Moderation = "on moderation"
+	Withdrawn  = "withdrawn"
 )
@@ -5,6 +5,19 @@ import "errors"
 var (
 	ErrUserType           = errors.New("wrong user type")
 	ErrUserExists         = errors.New("user already exists")
+	ErrUserNotFound       = errors.New("user not found")
 	ErrFlatStatus         = errors.New("wrong flat status")
+	ErrFlatTransition     = errors.New("flat status transition is not allowed")
+	ErrFlatNotFound       = errors.New("flat not found")
+	ErrHouseNotFound      = errors.New("house not found")
+	ErrFlatLocked         = errors.New("flat is on moderation by another moderator")
+	ErrFlatOwner          = errors.New("flat belongs to another user")
+	ErrQueueEmpty         = errors.New("moderation queue is empty")
+	ErrCursor             = errors.New("invalid cursor")
+	ErrDeclineReason      = errors.New("wrong decline reason")
 	ErrInvalidCredentials = errors.New("Invalid credentials")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrReferenceNotFound  = errors.New("referenced resource not found")
+	ErrConstraint         = errors.New("constraint violated")
 )
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type Comment struct {
+	ID        int       `json:"id" db:"id"`
+	FlatID    int       `json:"flat_id" db:"flat_id"`
+	AuthorID  string    `json:"author_id" db:"author_id"`
+	Text      string    `json:"text" db:"text"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
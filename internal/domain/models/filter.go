@@ -0,0 +1,47 @@
+package models
+
+const (
+	SortByPrice      = "price"
+	SortByRooms      = "rooms"
+	SortByFlatNumber = "flat_number"
+	SortByCreatedAt  = "created_at"
+)
+
+type FlatFilter struct {
+	MinPrice int
+	MaxPrice int
+	Rooms    int
+	Status   string
+	SortBy   string
+	Desc     bool
+	Limit    int
+	Cursor   *FlatCursor
+
+	// House filters, used by the search across houses.
+	MinYear   int
+	MaxYear   int
+	Developer string
+	Address   string
+}
+
+// FlatCursor points at the last flat of a page: the value of the sort column and the flat id.
+// It carries the sort it was issued for, so it cannot be replayed against another one.
+type FlatCursor struct {
+	SortBy string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  string `json:"v"`
+	ID     int    `json:"id"`
+}
+
+type FlatPage struct {
+	Flats      []Flat
+	NextCursor *FlatCursor
+}
+
+type HouseFilter struct {
+	MinYear   int
+	MaxYear   int
+	Developer string
+	Limit     int
+	Offset    int
+}
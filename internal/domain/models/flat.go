@@ -3,12 +3,33 @@ package models
 import "time"
 
 type Flat struct {
-	ID         int       `json:"id" db:"id"`
-	HouseID    int       `json:"house_id" db:"house_id"`
-	Price      int       `json:"price" db:"price"`
-	Rooms      int       `json:"rooms" db:"rooms"`
-	FlatNumber int       `json:"flat_number" db:"flat_number"`
-	Status     string    `json:"status" db:"status"`
-	CreatedAt  time.Time `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	ID                  int        `json:"id" db:"id"`
+	HouseID             int        `json:"house_id" db:"house_id"`
+	Price               int        `json:"price" db:"price"`
+	Rooms               int        `json:"rooms" db:"rooms"`
+	FlatNumber          int        `json:"flat_number" db:"flat_number"`
+	Status              string     `json:"status" db:"status"`
+	CreatedBy           *string    `json:"-" db:"created_by"`
+	ModeratorID         *string    `json:"-" db:"moderator_id"`
+	ModerationStartedAt *time.Time `json:"moderation_started_at,omitempty" db:"moderation_started_at"`
+	DeclineReason       *string    `json:"decline_reason,omitempty" db:"decline_reason"`
+	DeclineComment      *string    `json:"decline_comment,omitempty" db:"decline_comment"`
+	CreatedAt           time.Time  `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// OwnedBy reports whether the user created the flat. Flats of legacy rows and of
+// dummy users have no owner.
+func (f Flat) OwnedBy(userID string) bool {
+	return f.CreatedBy != nil && *f.CreatedBy == userID
+}
+
+// ModeratedBy reports whether the moderator took the flat on moderation or decided on it.
+func (f Flat) ModeratedBy(moderatorID string) bool {
+	return f.ModeratorID != nil && *f.ModeratorID == moderatorID
+}
+
+type DeclineReason struct {
+	Code    string `json:"decline_reason"`
+	Comment string `json:"decline_comment"`
 }
@@ -3,10 +3,27 @@ package models
 import "time"
 
 type House struct {
-	ID        int       `json:"id" db:"id"`
-	Address   string    `json:"address" db:"address"`
-	Year      int       `json:"year" db:"year"`
-	Developer string    `json:"developer,omitempty" db:"developer"`
-	CreatedAt time.Time `json:"created_at,omitempty" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at,omitempty" db:"updated_at"`
+	ID        int        `json:"id" db:"id"`
+	Address   string     `json:"address" db:"address"`
+	Year      int        `json:"year" db:"year"`
+	Developer string     `json:"developer,omitempty" db:"developer"`
+	CreatedAt time.Time  `json:"created_at,omitempty" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at,omitempty" db:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// HouseUpdate holds the house fields to change; nil fields are left as they are.
+type HouseUpdate struct {
+	Address   *string
+	Year      *int
+	Developer *string
+}
+
+// HouseSummary is a house with aggregates over its approved flats. The prices
+// are nil when the house has no approved flats.
+type HouseSummary struct {
+	House
+	FlatsCount int  `json:"flats_count" db:"flats_count"`
+	MinPrice   *int `json:"min_price,omitempty" db:"min_price"`
+	MaxPrice   *int `json:"max_price,omitempty" db:"max_price"`
 }
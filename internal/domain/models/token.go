@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Tokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type RefreshToken struct {
+	UserID    string    `db:"user_id"`
+	AccessJTI string    `db:"access_jti"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
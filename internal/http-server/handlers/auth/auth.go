@@ -1,41 +1,55 @@
 package authhandler
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 
-	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
 	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	authmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/auth"
 	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
 	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
 )
 
 type RequestRegister struct {
-	Email    string `json:"email" validate:"required"`
-	Password string `json:"password" validate:"required"`
-	UserType string `json:"user_type" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,password"`
+	UserType string `json:"user_type" validate:"required,user_type"`
 }
 
+// RequestLogin identifies the user either by email or by the id returned on registration.
 type RequestLogin struct {
-	Id       string `json:"id" validate:"required"`
+	Id       string `json:"id" validate:"required_without=Email,omitempty,id"`
+	Email    string `json:"email" validate:"required_without=Id,omitempty,email"`
 	Password string `json:"password" validate:"required"`
 }
 
+type RequestRefresh struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
 type AuthHandler struct {
 	log  *slog.Logger
 	user User
 }
 
 type User interface {
-	Login(email, password string) (string, error)
-	RegisterNewUser(email, password, userType string) (string, error)
-	GenerateToken(userID, email, userType string) (string, error)
+	Login(ctx context.Context, userID, email, password string) (models.Tokens, error)
+	Refresh(ctx context.Context, refreshToken string) (models.Tokens, error)
+	Logout(ctx context.Context, jti string, expiresAt time.Time) error
+	RevokeUser(ctx context.Context, userID string) error
+	RegisterNewUser(ctx context.Context, email, password, userType string) (string, error)
+	GenerateToken(ctx context.Context, userID, email, userType string) (string, error)
 }
 
 func New(
@@ -62,21 +76,21 @@ func (h *AuthHandler) RegisterNewUser(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, io.EOF) {
 			log.Error("request body is empty")
 
-			handlers.Error(w, r, http.StatusBadRequest, resp.Error("empty request", ""))
+			handlers.Error(w, r, http.StatusBadRequest, resp.Error("empty request"))
 
 			return
 		}
 
 		log.Error("failed to decode request body", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to decode request", middleware.GetReqID(r.Context())))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
 
 		return
 	}
 
 	log.Info("request body decoded", slog.Any("request", req))
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := handlers.Validate(req); err != nil {
 		validateErr := err.(validator.ValidationErrors)
 
 		log.Error("invalid request", sl.Err(err))
@@ -86,22 +100,9 @@ func (h *AuthHandler) RegisterNewUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := h.user.RegisterNewUser(req.Email, req.Password, req.UserType)
+	id, err := h.user.RegisterNewUser(r.Context(), req.Email, req.Password, req.UserType)
 	if err != nil {
-		if errors.Is(err, errs.ErrUserExists) {
-			handlers.Error(w, r, http.StatusBadRequest, resp.Error("user with this email already exists", ""))
-
-			return
-		}
-		if errors.Is(err, errs.ErrUserType) {
-			handlers.Error(w, r, http.StatusBadRequest, resp.Error("invalid user_type", ""))
-
-			return
-		}
-
-		log.Error("failed to register new user", sl.Err(err))
-
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to register new user", middleware.GetReqID(r.Context())))
+		handlers.ServiceError(w, r, log, err, "failed to register new user")
 
 		return
 	}
@@ -123,21 +124,21 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, io.EOF) {
 			log.Error("request body is empty")
 
-			handlers.Error(w, r, http.StatusBadRequest, resp.Error("empty request", ""))
+			handlers.Error(w, r, http.StatusBadRequest, resp.Error("empty request"))
 
 			return
 		}
 
 		log.Error("failed to decode request body", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to decode request", middleware.GetReqID(r.Context())))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
 
 		return
 	}
 
 	log.Info("request body decoded", slog.Any("request", req))
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := handlers.Validate(req); err != nil {
 		validateErr := err.(validator.ValidationErrors)
 
 		log.Error("invalid request", sl.Err(err))
@@ -147,22 +148,113 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.user.Login(req.Id, req.Password)
+	tokens, err := h.user.Login(r.Context(), req.Id, req.Email, req.Password)
 	if err != nil {
-		if errors.Is(err, errs.ErrInvalidCredentials) {
-			handlers.Error(w, r, http.StatusBadRequest, resp.Error("invalid credentials", ""))
+		handlers.ServiceError(w, r, log, err, "failed to login")
+
+		return
+	}
+
+	render.JSON(w, r, tokens)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.auth.Refresh"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	var req RequestRefresh
+	err := render.DecodeJSON(r.Body, &req)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			log.Error("request body is empty")
+
+			handlers.Error(w, r, http.StatusBadRequest, resp.Error("empty request"))
 
 			return
 		}
 
-		log.Error("failed to login", sl.Err(err))
+		log.Error("failed to decode request body", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to login", middleware.GetReqID(r.Context())))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
 
 		return
 	}
 
-	render.JSON(w, r, map[string]string{"token": token})
+	if err := handlers.Validate(req); err != nil {
+		validateErr := err.(validator.ValidationErrors)
+
+		log.Error("invalid request", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	tokens, err := h.user.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to refresh token")
+
+		return
+	}
+
+	render.JSON(w, r, tokens)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.auth.Logout"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	token, ok := r.Context().Value(authmid.TokenContextKey).(authmid.Token)
+	if !ok {
+		log.Error("token not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	if err := h.user.Logout(r.Context(), token.ID, token.ExpiresAt); err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to logout")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RevokeUser revokes every token of the user, so a moderator can sign out a
+// compromised or banned account.
+func (h *AuthHandler) RevokeUser(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.auth.RevokeUser"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	userID := chi.URLParam(r, "id")
+	if err := uuid.Validate(userID); err != nil {
+		log.Error("user id is not a uuid", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("user id is not a uuid"))
+
+		return
+	}
+
+	if err := h.user.RevokeUser(r.Context(), userID); err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to revoke user tokens")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *AuthHandler) DummyLogin(w http.ResponseWriter, r *http.Request) {
@@ -174,7 +266,7 @@ func (h *AuthHandler) DummyLogin(w http.ResponseWriter, r *http.Request) {
 	)
 
 	var req struct {
-		UserType string `json:"user_type" validate:"required"`
+		UserType string `json:"user_type" validate:"required,user_type"`
 	}
 
 	err := render.DecodeJSON(r.Body, &req)
@@ -182,21 +274,21 @@ func (h *AuthHandler) DummyLogin(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, io.EOF) {
 			log.Error("request body is empty")
 
-			handlers.Error(w, r, http.StatusBadRequest, resp.Error("empty request", ""))
+			handlers.Error(w, r, http.StatusBadRequest, resp.Error("empty request"))
 
 			return
 		}
 
 		log.Error("failed to decode request body", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to decode request", middleware.GetReqID(r.Context())))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request"))
 
 		return
 	}
 
 	log.Info("request body decoded", slog.Any("request", req))
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := handlers.Validate(req); err != nil {
 		validateErr := err.(validator.ValidationErrors)
 
 		log.Error("invalid request", sl.Err(err))
@@ -207,11 +299,9 @@ func (h *AuthHandler) DummyLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Генерация токена
-	token, err := h.user.GenerateToken("dummyID", "dummy@example.com", req.UserType)
+	token, err := h.user.GenerateToken(r.Context(), "dummyID", "dummy@example.com", req.UserType)
 	if err != nil {
-		log.Error("failed to generate token", sl.Err(err))
-
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to generate token", middleware.GetReqID(r.Context())))
+		handlers.ServiceError(w, r, log, err, "failed to generate token")
 
 		return
 	}
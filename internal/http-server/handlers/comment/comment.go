@@ -0,0 +1,170 @@
+package commenthandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	authmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/auth"
+	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
+	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+)
+
+type Request struct {
+	Text string `json:"text" validate:"required"`
+}
+
+type CommentHandler struct {
+	log     *slog.Logger
+	comment Comment
+	flat    Flat
+}
+
+type Comment interface {
+	SaveComment(ctx context.Context, flatID int, authorID, text string) (models.Comment, error)
+	Comments(ctx context.Context, flatID int) ([]models.Comment, error)
+}
+
+// Flat returns the flat if the user may see it.
+type Flat interface {
+	Flat(ctx context.Context, userID, userType string, flatID int) (models.Flat, error)
+}
+
+func New(log *slog.Logger, comment Comment, flat Flat) *CommentHandler {
+	return &CommentHandler{
+		log:     log,
+		comment: comment,
+		flat:    flat,
+	}
+}
+
+func (h *CommentHandler) SaveComment(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.comment.SaveComment"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("flat id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("flat id is not a number"))
+
+		return
+	}
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	var req Request
+	err = render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request body"))
+
+		return
+	}
+
+	if err := handlers.Validate(req); err != nil {
+		validateErr := err.(validator.ValidationErrors)
+
+		log.Error("invalid request", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	// Flats of deleted houses are kept in the database, but must not get comments.
+	if _, err := h.flat.Flat(r.Context(), user.Id, user.UserType, flatID); err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get flat")
+
+		return
+	}
+
+	comment, err := h.comment.SaveComment(r.Context(), flatID, user.Id, req.Text)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to save comment")
+
+		return
+	}
+
+	render.JSON(w, r, comment)
+}
+
+func (h *CommentHandler) Comments(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.comment.Comments"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("flat id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("flat id is not a number"))
+
+		return
+	}
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flat, err := h.flat.Flat(r.Context(), user.Id, user.UserType, flatID)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get flat")
+
+		return
+	}
+
+	if user.UserType != constants.Admin && !flat.OwnedBy(user.Id) {
+		log.Info("comments requested by a user who does not own the flat")
+
+		handlers.Error(w, r, http.StatusForbidden, resp.Error("only moderators and the seller can read comments"))
+
+		return
+	}
+
+	comments, err := h.comment.Comments(r.Context(), flatID)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get comments")
+
+		return
+	}
+
+	render.JSON(w, r, comments)
+}
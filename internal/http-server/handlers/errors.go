@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
+	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+)
+
+// domainError is the HTTP representation of a domain error.
+type domainError struct {
+	err    error
+	status int
+	code   string
+}
+
+// domainErrors is the single mapping from domain errors to statuses and stable codes.
+// Specific errors come before the generic constraint ones.
+var domainErrors = []domainError{
+	{errs.ErrUserType, http.StatusBadRequest, "user_type_invalid"},
+	{errs.ErrUserExists, http.StatusConflict, "user_exists"},
+	{errs.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+	{errs.ErrInvalidCredentials, http.StatusBadRequest, "invalid_credentials"},
+	{errs.ErrInvalidToken, http.StatusUnauthorized, "invalid_token"},
+	{errs.ErrFlatStatus, http.StatusBadRequest, "flat_status_invalid"},
+	{errs.ErrDeclineReason, http.StatusBadRequest, "decline_reason_invalid"},
+	{errs.ErrFlatTransition, http.StatusConflict, "flat_transition_not_allowed"},
+	{errs.ErrFlatLocked, http.StatusConflict, "flat_locked"},
+	{errs.ErrFlatOwner, http.StatusForbidden, "flat_owner_mismatch"},
+	{errs.ErrFlatNotFound, http.StatusNotFound, "flat_not_found"},
+	{errs.ErrHouseNotFound, http.StatusNotFound, "house_not_found"},
+	{errs.ErrQueueEmpty, http.StatusNoContent, "queue_empty"},
+	{errs.ErrCursor, http.StatusBadRequest, "cursor_invalid"},
+	{errs.ErrAlreadyExists, http.StatusConflict, "already_exists"},
+	{errs.ErrReferenceNotFound, http.StatusNotFound, "reference_not_found"},
+	{errs.ErrConstraint, http.StatusBadRequest, "constraint_violated"},
+	{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+}
+
+// ServiceError writes the problem for an error returned by a service or storage.
+// Domain errors get their mapped status and code, or no body for 204; anything
+// else is logged and answered with 500 and msg.
+func ServiceError(w http.ResponseWriter, r *http.Request, log *slog.Logger, err error, msg string) {
+	for _, e := range domainErrors {
+		if errors.Is(err, e.err) {
+			log.Info(msg, sl.Err(err))
+
+			if e.status == http.StatusNoContent {
+				w.WriteHeader(e.status)
+
+				return
+			}
+
+			Error(w, r, e.status, resp.Problem{
+				Detail: e.err.Error(),
+				Code:   e.code,
+			})
+
+			return
+		}
+	}
+
+	log.Error(msg, sl.Err(err))
+
+	Error(w, r, http.StatusInternalServerError, resp.Error(msg))
+}
@@ -1,45 +1,59 @@
 package flathandler
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 
-	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/zanzhit/flat-seller/internal/domain/models"
 	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	authmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/auth"
 	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
 	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
 )
 
 type Request struct {
-	Price  int    `json:"price" validate:"required"`
-	Room   int    `json:"room" validate:"required"`
+	Price  int    `json:"price" validate:"required,price"`
+	Rooms  int    `json:"rooms" validate:"required,rooms"`
 	Status string `json:"status"`
 }
 
 type UpdateRequest struct {
-	Id int `json:"id" validate:"required"`
+	Id int `json:"id" validate:"required,id"`
 	Request
+	models.DeclineReason
 }
 
 type SaveRequest struct {
-	Id int `json:"house_id" validate:"required"`
+	Id int `json:"house_id" validate:"required,id"`
 	Request
 }
 
+type EditRequest struct {
+	Price int `json:"price" validate:"required,price"`
+	Rooms int `json:"rooms" validate:"required,rooms"`
+}
+
 type FlatHandler struct {
 	log  *slog.Logger
 	flat Flat
 }
 
 type Flat interface {
-	SaveFlat(houseID, price, rooms int) (models.Flat, error)
-	UpdateFlat(flatID, price, rooms int, status string) (models.Flat, error)
+	SaveFlat(ctx context.Context, ownerID string, houseID, price, rooms int) (models.Flat, error)
+	UpdateFlat(ctx context.Context, moderatorID string, flatID, price, rooms int, status string, reason models.DeclineReason) (models.Flat, error)
+	OwnerFlats(ctx context.Context, ownerID string) ([]models.Flat, error)
+	EditFlat(ctx context.Context, ownerID string, flatID, price, rooms int) (models.Flat, error)
+	WithdrawFlat(ctx context.Context, ownerID string, flatID int) (models.Flat, error)
+	Flat(ctx context.Context, userID, userType string, flatID int) (models.Flat, error)
 }
 
 func New(
@@ -65,7 +79,7 @@ func (h *FlatHandler) SaveFlat(w http.ResponseWriter, r *http.Request) {
 	if errors.Is(err, io.EOF) {
 		log.Error("request body is empty")
 
-		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty", ""))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty"))
 
 		return
 	}
@@ -73,14 +87,14 @@ func (h *FlatHandler) SaveFlat(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Error("failed to decode request body", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to decode request body", middleware.GetReqID(r.Context())))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request body"))
 
 		return
 	}
 
 	log.Info("request body decoded", slog.Any("request", req))
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := handlers.Validate(req); err != nil {
 		validateErr := err.(validator.ValidationErrors)
 
 		log.Error("invalid request", sl.Err(err))
@@ -90,13 +104,22 @@ func (h *FlatHandler) SaveFlat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	flat, err := h.flat.SaveFlat(req.Id, req.Price, req.Room)
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flat, err := h.flat.SaveFlat(r.Context(), user.Id, req.Id, req.Price, req.Rooms)
 	if err != nil {
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to save flat", middleware.GetReqID(r.Context())))
+		handlers.ServiceError(w, r, log, err, "failed to save flat")
+
 		return
 	}
 
-	render.JSON(w, r, flat)
+	render.JSON(w, r, handlers.FlatView(flat, user))
 }
 
 func (h *FlatHandler) UpdateFlat(w http.ResponseWriter, r *http.Request) {
@@ -112,7 +135,7 @@ func (h *FlatHandler) UpdateFlat(w http.ResponseWriter, r *http.Request) {
 	if errors.Is(err, io.EOF) {
 		log.Error("request body is empty")
 
-		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty", ""))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty"))
 
 		return
 	}
@@ -120,14 +143,14 @@ func (h *FlatHandler) UpdateFlat(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Error("failed to decode request body", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to decode request body", middleware.GetReqID(r.Context())))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request body"))
 
 		return
 	}
 
 	log.Info("request body decoded", slog.Any("request", req))
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := handlers.Validate(req); err != nil {
 		validateErr := err.(validator.ValidationErrors)
 
 		log.Error("invalid request", sl.Err(err))
@@ -137,11 +160,182 @@ func (h *FlatHandler) UpdateFlat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	flat, err := h.flat.UpdateFlat(req.Id, req.Price, req.Room, req.Status)
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flat, err := h.flat.UpdateFlat(r.Context(), user.Id, req.Id, req.Price, req.Rooms, req.Status, req.DeclineReason)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to save flat")
+
+		return
+	}
+
+	render.JSON(w, r, handlers.FlatView(flat, user))
+}
+
+func (h *FlatHandler) OwnerFlats(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.flat.OwnerFlats"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flats, err := h.flat.OwnerFlats(r.Context(), user.Id)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get flats")
+
+		return
+	}
+
+	render.JSON(w, r, handlers.FlatViews(flats, user))
+}
+
+func (h *FlatHandler) EditFlat(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.flat.EditFlat"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
 	if err != nil {
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to save flat", middleware.GetReqID(r.Context())))
+		log.Error("flat id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("flat id is not a number"))
+
+		return
+	}
+
+	var req EditRequest
+	err = render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request body"))
+
+		return
+	}
+
+	log.Info("request body decoded", slog.Any("request", req))
+
+	if err := handlers.Validate(req); err != nil {
+		validateErr := err.(validator.ValidationErrors)
+
+		log.Error("invalid request", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flat, err := h.flat.EditFlat(r.Context(), user.Id, flatID, req.Price, req.Rooms)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to update flat")
+
+		return
+	}
+
+	render.JSON(w, r, handlers.FlatView(flat, user))
+}
+
+func (h *FlatHandler) WithdrawFlat(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.flat.WithdrawFlat"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("flat id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("flat id is not a number"))
+
+		return
+	}
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flat, err := h.flat.WithdrawFlat(r.Context(), user.Id, flatID)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to update flat")
+
+		return
+	}
+
+	render.JSON(w, r, handlers.FlatView(flat, user))
+}
+
+// Flat returns a single flat. Clients only see approved flats and their own ones.
+func (h *FlatHandler) Flat(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.flat.Flat"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	flatID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("flat id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("flat id is not a number"))
+
+		return
+	}
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flat, err := h.flat.Flat(r.Context(), user.Id, user.UserType, flatID)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get flat")
+
 		return
 	}
 
-	render.JSON(w, r, flat)
+	render.JSON(w, r, handlers.FlatView(flat, user))
 }
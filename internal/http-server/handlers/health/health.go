@@ -0,0 +1,115 @@
+package healthhandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-chi/render"
+
+	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+)
+
+const (
+	statusOK       = "ok"
+	statusFailed   = "failed"
+	statusDraining = "draining"
+)
+
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type HealthHandler struct {
+	log           *slog.Logger
+	health        Health
+	latestVersion uint
+	draining      atomic.Bool
+}
+
+type Health interface {
+	Ping(ctx context.Context) error
+	MigrationVersion(ctx context.Context) (uint, bool, error)
+}
+
+// New creates the handler. latestVersion is the newest migration the server expects;
+// zero disables the migrations check.
+func New(log *slog.Logger, health Health, latestVersion uint) *HealthHandler {
+	return &HealthHandler{
+		log:           log,
+		health:        health,
+		latestVersion: latestVersion,
+	}
+}
+
+// Drain makes the readiness probe fail, so traffic stops before the server shuts down.
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, Response{Status: statusOK})
+}
+
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.health.Readiness"
+
+	log := h.log.With(
+		slog.String("op", op),
+	)
+
+	if h.draining.Load() {
+		render.Status(r, http.StatusServiceUnavailable)
+		render.JSON(w, r, Response{Status: statusDraining})
+
+		return
+	}
+
+	res := Response{
+		Status: statusOK,
+		Checks: map[string]string{},
+	}
+
+	if err := h.health.Ping(r.Context()); err != nil {
+		log.Error("database is unavailable", sl.Err(err))
+
+		res.Status = statusFailed
+		res.Checks["database"] = statusFailed
+	} else {
+		res.Checks["database"] = statusOK
+	}
+
+	if h.latestVersion > 0 {
+		res.Checks["migrations"] = h.checkMigrations(r.Context(), log)
+		if res.Checks["migrations"] != statusOK {
+			res.Status = statusFailed
+		}
+	}
+
+	if res.Status != statusOK {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+
+	render.JSON(w, r, res)
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context, log *slog.Logger) string {
+	version, dirty, err := h.health.MigrationVersion(ctx)
+	if err != nil {
+		log.Error("failed to get migration version", sl.Err(err))
+
+		return statusFailed
+	}
+
+	if dirty {
+		return "dirty"
+	}
+
+	if version < h.latestVersion {
+		return "pending"
+	}
+
+	return statusOK
+}
@@ -1,42 +1,65 @@
 package househandler
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
 
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 
 	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
 	"github.com/zanzhit/flat-seller/internal/domain/models"
 	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
 	authmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/auth"
 	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
+	"github.com/zanzhit/flat-seller/internal/lib/cursor"
 	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
 )
 
 type Request struct {
-	Year      int    `json:"year" validate:"required"`
+	Year      int    `json:"year" validate:"required,year"`
 	Developer string `json:"developer,omitempty"`
 	Address   string `json:"address" validate:"required"`
 }
 
+// UpdateRequest changes only the fields that are present.
+type UpdateRequest struct {
+	Address   *string `json:"address,omitempty" validate:"omitempty,min=1"`
+	Year      *int    `json:"year,omitempty" validate:"omitempty,year"`
+	Developer *string `json:"developer,omitempty"`
+}
+
 type HouseHandler struct {
 	log   *slog.Logger
 	house House
 }
 
 type House interface {
-	SaveHouse(address, developer string, year int) (models.House, error)
-	HouseUser(houseID int) ([]models.Flat, error)
-	HouseAdmin(houseID int) ([]models.Flat, error)
+	SaveHouse(ctx context.Context, address, developer string, year int) (models.House, error)
+	HouseUser(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error)
+	HouseAdmin(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error)
+	SearchUser(ctx context.Context, filter models.FlatFilter) (models.FlatPage, error)
+	SearchAdmin(ctx context.Context, filter models.FlatFilter) (models.FlatPage, error)
+	Houses(ctx context.Context, filter models.HouseFilter) ([]models.HouseSummary, error)
+	UpdateHouse(ctx context.Context, houseID int, update models.HouseUpdate) (models.House, error)
+	DeleteHouse(ctx context.Context, houseID int) error
+	HardDeleteHouse(ctx context.Context, houseID int) error
 }
 
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+
+	nextCursorHeader = "X-Next-Cursor"
+)
+
 func New(log *slog.Logger, house House) *HouseHandler {
 	return &HouseHandler{
 		log:   log,
@@ -57,12 +80,20 @@ func (h *HouseHandler) SaveHouse(w http.ResponseWriter, r *http.Request) {
 	if errors.Is(err, io.EOF) {
 		log.Error("request body is empty")
 
-		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty", ""))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request body"))
 
 		return
 	}
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := handlers.Validate(req); err != nil {
 		validateErr := err.(validator.ValidationErrors)
 
 		log.Error("invalid request", sl.Err(err))
@@ -72,11 +103,78 @@ func (h *HouseHandler) SaveHouse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	house, err := h.house.SaveHouse(req.Address, req.Developer, req.Year)
+	house, err := h.house.SaveHouse(r.Context(), req.Address, req.Developer, req.Year)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to save house")
+
+		return
+	}
+
+	render.JSON(w, r, house)
+}
+
+func (h *HouseHandler) UpdateHouse(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.house.UpdateHouse"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	houseID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("house id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("house id is not a number"))
+
+		return
+	}
+
+	var req UpdateRequest
+	err = render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty"))
+
+		return
+	}
+
 	if err != nil {
-		log.Error("failed to save house", sl.Err(err))
+		log.Error("failed to decode request body", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to save house", ""))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request body"))
+
+		return
+	}
+
+	log.Info("request body decoded", slog.Any("request", req))
+
+	if err := handlers.Validate(req); err != nil {
+		validateErr := err.(validator.ValidationErrors)
+
+		log.Error("invalid request", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	if req.Address == nil && req.Year == nil && req.Developer == nil {
+		log.Error("nothing to update")
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("address, year or developer is required"))
+
+		return
+	}
+
+	house, err := h.house.UpdateHouse(r.Context(), houseID, models.HouseUpdate{
+		Address:   req.Address,
+		Year:      req.Year,
+		Developer: req.Developer,
+	})
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to update house")
 
 		return
 	}
@@ -84,6 +182,52 @@ func (h *HouseHandler) SaveHouse(w http.ResponseWriter, r *http.Request) {
 	render.JSON(w, r, house)
 }
 
+// DeleteHouse soft deletes the house, or removes it with its flats and
+// subscriptions when the hard query parameter is true.
+func (h *HouseHandler) DeleteHouse(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.house.DeleteHouse"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	houseID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("house id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("house id is not a number"))
+
+		return
+	}
+
+	hard := false
+	if value := r.URL.Query().Get("hard"); value != "" {
+		if hard, err = strconv.ParseBool(value); err != nil {
+			log.Error("invalid hard flag", sl.Err(err))
+
+			handlers.Error(w, r, http.StatusBadRequest, resp.Error("hard must be true or false"))
+
+			return
+		}
+	}
+
+	if hard {
+		err = h.house.HardDeleteHouse(r.Context(), houseID)
+	} else {
+		err = h.house.DeleteHouse(r.Context(), houseID)
+	}
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to delete house")
+
+		return
+	}
+
+	log.Info("house deleted", slog.Int("house_id", houseID), slog.Bool("hard", hard))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *HouseHandler) House(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.house.HouseUser"
 
@@ -98,7 +242,7 @@ func (h *HouseHandler) House(w http.ResponseWriter, r *http.Request) {
 	if houseID == "" {
 		log.Error("houseID is empty")
 
-		handlers.Error(w, r, http.StatusBadRequest, resp.Error("houseID is empty", ""))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("houseID is empty"))
 
 		return
 	}
@@ -107,31 +251,220 @@ func (h *HouseHandler) House(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Error("house id is not a number", sl.Err(err))
 
-		handlers.Error(w, r, http.StatusBadRequest, resp.Error("house id is not a number", ""))
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("house id is not a number"))
+
+		return
+	}
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		h.log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		log.Error("invalid filter", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error(err.Error()))
 
 		return
 	}
 
+	var page models.FlatPage
+	if user.UserType == constants.Admin {
+		page, err = h.house.HouseAdmin(r.Context(), id, filter)
+	} else {
+		page, err = h.house.HouseUser(r.Context(), id, filter)
+	}
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get flats")
+
+		return
+	}
+
+	renderPage(w, r, log, page, user)
+}
+
+func (h *HouseHandler) Search(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.house.Search"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
 	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
 	if !ok {
 		h.log.Error("user not found in context")
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	filter, err := parseSearchFilter(r)
+	if err != nil {
+		log.Error("invalid filter", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error(err.Error()))
+
 		return
 	}
 
-	var flats []models.Flat
+	var page models.FlatPage
 	if user.UserType == constants.Admin {
-		flats, err = h.house.HouseAdmin(id)
+		page, err = h.house.SearchAdmin(r.Context(), filter)
 	} else {
-		flats, err = h.house.HouseUser(id)
+		page, err = h.house.SearchUser(r.Context(), filter)
 	}
 	if err != nil {
-		log.Error("failed to get flats", sl.Err(err))
+		handlers.ServiceError(w, r, log, err, "failed to search flats")
+
+		return
+	}
 
-		handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to get flats", ""))
+	renderPage(w, r, log, page, user)
+}
+
+// Houses lists houses with the number and price range of their approved flats.
+func (h *HouseHandler) Houses(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.house.Houses"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	filter, err := parseHouseFilter(r)
+	if err != nil {
+		log.Error("invalid filter", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error(err.Error()))
 
 		return
 	}
 
-	render.JSON(w, r, flats)
+	houses, err := h.house.Houses(r.Context(), filter)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get houses")
+
+		return
+	}
+
+	render.JSON(w, r, houses)
+}
+
+func renderPage(w http.ResponseWriter, r *http.Request, log *slog.Logger, page models.FlatPage, user models.User) {
+	if page.NextCursor != nil {
+		next, err := cursor.Encode(*page.NextCursor)
+		if err != nil {
+			handlers.ServiceError(w, r, log, err, "failed to encode cursor")
+
+			return
+		}
+
+		w.Header().Set(nextCursorHeader, next)
+	}
+
+	render.JSON(w, r, handlers.FlatViews(page.Flats, user))
+}
+
+// parseFilter reads pagination, sorting and filtering query parameters.
+// The status filter is ignored for clients, who only see approved flats.
+func parseFilter(r *http.Request) (models.FlatFilter, error) {
+	query := r.URL.Query()
+
+	filter := models.FlatFilter{
+		SortBy: query.Get("sort_by"),
+		Status: query.Get("status"),
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = models.SortByFlatNumber
+	case models.SortByPrice, models.SortByRooms, models.SortByFlatNumber, models.SortByCreatedAt:
+	default:
+		return models.FlatFilter{}, errors.New("sort_by must be one of price, rooms, flat_number, created_at")
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return models.FlatFilter{}, errors.New("order must be asc or desc")
+	}
+
+	var err error
+	if filter.Limit, err = handlers.QueryInt(r, "limit", defaultLimit); err != nil || filter.Limit <= 0 || filter.Limit > maxLimit {
+		return models.FlatFilter{}, errors.New("limit must be a number from 1 to 100")
+	}
+	if filter.MinPrice, err = handlers.QueryInt(r, "min_price", 0); err != nil || filter.MinPrice < 0 {
+		return models.FlatFilter{}, errors.New("min_price must be a non-negative number")
+	}
+	if filter.MaxPrice, err = handlers.QueryInt(r, "max_price", 0); err != nil || filter.MaxPrice < 0 {
+		return models.FlatFilter{}, errors.New("max_price must be a non-negative number")
+	}
+	if filter.Rooms, err = handlers.QueryInt(r, "rooms", 0); err != nil || filter.Rooms < 0 {
+		return models.FlatFilter{}, errors.New("rooms must be a non-negative number")
+	}
+
+	if c := query.Get("cursor"); c != "" {
+		decoded, err := cursor.Decode(c)
+		if err != nil {
+			return models.FlatFilter{}, errs.ErrCursor
+		}
+
+		if err := cursor.Check(decoded, filter.SortBy, filter.Desc); err != nil {
+			return models.FlatFilter{}, err
+		}
+
+		filter.Cursor = &decoded
+	}
+
+	return filter, nil
+}
+
+// parseSearchFilter reads the flat filter together with the house filters.
+func parseSearchFilter(r *http.Request) (models.FlatFilter, error) {
+	filter, err := parseFilter(r)
+	if err != nil {
+		return models.FlatFilter{}, err
+	}
+
+	if filter.MinYear, err = handlers.QueryInt(r, "min_year", 0); err != nil || filter.MinYear < 0 {
+		return models.FlatFilter{}, errors.New("min_year must be a non-negative number")
+	}
+	if filter.MaxYear, err = handlers.QueryInt(r, "max_year", 0); err != nil || filter.MaxYear < 0 {
+		return models.FlatFilter{}, errors.New("max_year must be a non-negative number")
+	}
+
+	filter.Developer = r.URL.Query().Get("developer")
+	filter.Address = r.URL.Query().Get("address")
+
+	return filter, nil
+}
+
+func parseHouseFilter(r *http.Request) (models.HouseFilter, error) {
+	filter := models.HouseFilter{
+		Developer: r.URL.Query().Get("developer"),
+	}
+
+	var err error
+	if filter.Limit, err = handlers.QueryInt(r, "limit", defaultLimit); err != nil || filter.Limit <= 0 || filter.Limit > maxLimit {
+		return models.HouseFilter{}, errors.New("limit must be a number from 1 to 100")
+	}
+	if filter.Offset, err = handlers.QueryInt(r, "offset", 0); err != nil || filter.Offset < 0 {
+		return models.HouseFilter{}, errors.New("offset must be a non-negative number")
+	}
+	if filter.MinYear, err = handlers.QueryInt(r, "min_year", 0); err != nil || filter.MinYear < 0 {
+		return models.HouseFilter{}, errors.New("min_year must be a non-negative number")
+	}
+	if filter.MaxYear, err = handlers.QueryInt(r, "max_year", 0); err != nil || filter.MaxYear < 0 {
+		return models.HouseFilter{}, errors.New("max_year must be a non-negative number")
+	}
+
+	return filter, nil
 }
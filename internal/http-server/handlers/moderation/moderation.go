@@ -0,0 +1,107 @@
+package moderationhandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	authmid "github.com/zanzhit/flat-seller/internal/http-server/middleware/auth"
+	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+type ModerationHandler struct {
+	log        *slog.Logger
+	moderation Moderation
+}
+
+type Moderation interface {
+	Queue(ctx context.Context, limit, offset int) ([]models.Flat, error)
+	TakeNext(ctx context.Context, moderatorID string) (models.Flat, error)
+}
+
+func New(log *slog.Logger, moderation Moderation) *ModerationHandler {
+	return &ModerationHandler{
+		log:        log,
+		moderation: moderation,
+	}
+}
+
+func (h *ModerationHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.moderation.Queue"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	limit, err := handlers.QueryInt(r, "limit", defaultLimit)
+	if err != nil || limit <= 0 || limit > maxLimit {
+		log.Error("invalid limit", slog.String("limit", r.URL.Query().Get("limit")))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("limit must be a number from 1 to 100"))
+
+		return
+	}
+
+	offset, err := handlers.QueryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		log.Error("invalid offset", slog.String("offset", r.URL.Query().Get("offset")))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("offset must be a non-negative number"))
+
+		return
+	}
+
+	flats, err := h.moderation.Queue(r.Context(), limit, offset)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to get moderation queue")
+
+		return
+	}
+
+	render.JSON(w, r, handlers.FlatViews(flats, user))
+}
+
+func (h *ModerationHandler) TakeNext(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.moderation.TakeNext"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	user, ok := r.Context().Value(authmid.UserContextKey).(models.User)
+	if !ok {
+		log.Error("user not found in context")
+		handlers.Error(w, r, http.StatusUnauthorized, resp.Error("user not found in context"))
+
+		return
+	}
+
+	flat, err := h.moderation.TakeNext(r.Context(), user.Id)
+	if err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to take flat on moderation")
+
+		return
+	}
+
+	render.JSON(w, r, handlers.FlatView(flat, user))
+}
@@ -1,13 +1,57 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
 
-	"github.com/go-chi/render"
 	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
 )
 
-func Error(w http.ResponseWriter, r *http.Request, statusCode int, err resp.Response) {
+// retryAfter is the number of seconds clients should wait before retrying after a 5xx.
+const retryAfter = 5
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:   resp.CodeBadRequest,
+	http.StatusUnauthorized: resp.CodeUnauthorized,
+	http.StatusForbidden:    resp.CodeForbidden,
+	http.StatusNotFound:     resp.CodeNotFound,
+	http.StatusConflict:     resp.CodeConflict,
+}
+
+// Error writes the problem as application/problem+json. The code defaults to one
+// derived from the status, and the request id is always taken from the request.
+func Error(w http.ResponseWriter, r *http.Request, statusCode int, problem resp.Problem) {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(statusCode)
+	problem.Status = statusCode
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.GetReqID(r.Context())
+
+	if problem.Code == "" {
+		problem.Code = statusCodes[statusCode]
+	}
+	if problem.Code == "" {
+		problem.Code = resp.CodeInternal
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+
+	w.Header().Set("Content-Type", resp.ContentType)
 	w.WriteHeader(statusCode)
-	render.JSON(w, r, err)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// QueryInt returns the integer query parameter or def if the parameter is absent.
+func QueryInt(r *http.Request, key string, def int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(value)
 }
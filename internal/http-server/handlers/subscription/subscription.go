@@ -0,0 +1,95 @@
+package subscriptionhandler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
+	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+)
+
+type Request struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type SubscriptionHandler struct {
+	log          *slog.Logger
+	subscription Subscription
+}
+
+type Subscription interface {
+	Subscribe(ctx context.Context, houseID int, email string) error
+}
+
+func New(log *slog.Logger, subscription Subscription) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		log:          log,
+		subscription: subscription,
+	}
+}
+
+func (h *SubscriptionHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.subscription.Subscribe"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.String("request_id", middleware.GetReqID(r.Context())),
+	)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Error("house id is not a number", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("house id is not a number"))
+
+		return
+	}
+
+	var req Request
+	err = render.DecodeJSON(r.Body, &req)
+	if errors.Is(err, io.EOF) {
+		log.Error("request body is empty")
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("request body is empty"))
+
+		return
+	}
+
+	if err != nil {
+		log.Error("failed to decode request body", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.Error("failed to decode request body"))
+
+		return
+	}
+
+	log.Info("request body decoded", slog.Any("request", req))
+
+	if err := handlers.Validate(req); err != nil {
+		validateErr := err.(validator.ValidationErrors)
+
+		log.Error("invalid request", sl.Err(err))
+
+		handlers.Error(w, r, http.StatusBadRequest, resp.ValidationError(validateErr))
+
+		return
+	}
+
+	if err := h.subscription.Subscribe(r.Context(), id, req.Email); err != nil {
+		handlers.ServiceError(w, r, log, err, "failed to subscribe")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
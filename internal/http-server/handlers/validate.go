@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"reflect"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt ignores everything after the 72nd byte.
+	maxPasswordLength = 72
+
+	minPrice = 1
+	maxPrice = 1_000_000_000
+
+	minRooms = 1
+	maxRooms = 20
+
+	minYear = 1800
+	// Houses under construction may be listed a few years ahead.
+	maxYearsAhead = 10
+)
+
+// validate is shared by all handlers: validator caches struct metadata, so it
+// must not be created per request.
+var validate = newValidator()
+
+// Validate checks the struct against its validate tags, including the custom ones:
+// password, user_type, id, price, rooms and year.
+func Validate(s any) error {
+	return validate.Struct(s)
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	rules := map[string]validator.Func{
+		"password":  isPassword,
+		"user_type": isUserType,
+		"id":        isID,
+		"price":     intBetween(minPrice, maxPrice),
+		"rooms":     intBetween(minRooms, maxRooms),
+		"year":      isYear,
+	}
+	for tag, fn := range rules {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			panic(err)
+		}
+	}
+
+	return v
+}
+
+// isPassword requires 8 to 72 bytes with at least one letter and one digit.
+func isPassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return false
+	}
+
+	var letter, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+
+	return letter && digit
+}
+
+func isUserType(fl validator.FieldLevel) bool {
+	userType := fl.Field().String()
+
+	return userType == constants.User || userType == constants.Admin
+}
+
+// isID accepts positive integer ids of houses and flats and UUID ids of users.
+func isID(fl validator.FieldLevel) bool {
+	field := fl.Field()
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int() > 0
+	case reflect.String:
+		return uuid.Validate(field.String()) == nil
+	default:
+		return false
+	}
+}
+
+func intBetween(min, max int64) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		value := fl.Field().Int()
+
+		return value >= min && value <= max
+	}
+}
+
+func isYear(fl validator.FieldLevel) bool {
+	year := fl.Field().Int()
+
+	return year >= minYear && year <= int64(time.Now().Year()+maxYearsAhead)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+)
+
+// Flat is the flat as rendered to a user. Who owns and who moderates the flat and
+// why it was declined is only shown to moderators and to the owner.
+type Flat struct {
+	models.Flat
+	CreatedBy   *string `json:"created_by,omitempty"`
+	ModeratorID *string `json:"moderator_id,omitempty"`
+}
+
+// FlatView returns the flat as the user may see it.
+func FlatView(flat models.Flat, user models.User) Flat {
+	if user.UserType == constants.Admin || flat.OwnedBy(user.Id) {
+		return Flat{
+			Flat:        flat,
+			CreatedBy:   flat.CreatedBy,
+			ModeratorID: flat.ModeratorID,
+		}
+	}
+
+	flat.DeclineReason = nil
+	flat.DeclineComment = nil
+
+	return Flat{Flat: flat}
+}
+
+// FlatViews returns the flats as the user may see them.
+func FlatViews(flats []models.Flat, user models.User) []Flat {
+	views := make([]Flat, len(flats))
+	for i, flat := range flats {
+		views[i] = FlatView(flat, user)
+	}
+
+	return views
+}
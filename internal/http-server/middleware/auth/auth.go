@@ -4,24 +4,38 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/zanzhit/flat-seller/internal/domain/constants"
 	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
 )
 
 type contextKey string
 
 const (
-	UserContextKey contextKey = "user"
+	UserContextKey  contextKey = "user"
+	TokenContextKey contextKey = "token"
 )
 
-func JWTAuth(secret string) func(http.Handler) http.Handler {
+// Token describes the access token the request was authorized with.
+type Token struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error)
+}
+
+func JWTAuth(secret string, revocation RevocationChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
 				return
 			}
 
@@ -31,23 +45,66 @@ func JWTAuth(secret string) func(http.Handler) http.Handler {
 			})
 
 			if err != nil || !token.Valid {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
 				return
 			}
 
 			claims, ok := token.Claims.(jwt.MapClaims)
 			if !ok {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
+				return
+			}
+
+			jti, ok := claims["jti"].(string)
+			if !ok || jti == "" {
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
+				return
+			}
+
+			exp, err := claims.GetExpirationTime()
+			if err != nil || exp == nil {
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
+				return
+			}
+
+			uid, _ := claims["uid"].(string)
+			email, _ := claims["email"].(string)
+			userType, _ := claims["user_type"].(string)
+			if uid == "" || userType == "" {
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
+				return
+			}
+
+			// Tokens issued before iat was added are treated as the oldest ones.
+			var issuedAt time.Time
+			iat, err := claims.GetIssuedAt()
+			if err != nil {
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
+				return
+			}
+			if iat != nil {
+				issuedAt = iat.Time
+			}
+
+			revoked, err := revocation.IsRevoked(r.Context(), jti, uid, issuedAt)
+			if err != nil {
+				handlers.Error(w, r, http.StatusInternalServerError, resp.Error("failed to check token"))
+				return
+			}
+
+			if revoked {
+				handlers.Error(w, r, http.StatusUnauthorized, resp.Error("unauthorized"))
 				return
 			}
 
 			user := models.User{
-				Id:       claims["uid"].(string),
-				Email:    claims["email"].(string),
-				UserType: claims["user_type"].(string),
+				Id:       uid,
+				Email:    email,
+				UserType: userType,
 			}
 
 			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			ctx = context.WithValue(ctx, TokenContextKey, Token{ID: jti, ExpiresAt: exp.Time})
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -57,7 +114,7 @@ func AdminRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user, ok := r.Context().Value(UserContextKey).(models.User)
 		if !ok || user.UserType != constants.Admin {
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			handlers.Error(w, r, http.StatusForbidden, resp.Error("admin access required"))
 			return
 		}
 
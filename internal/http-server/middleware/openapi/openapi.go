@@ -0,0 +1,204 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/zanzhit/flat-seller/internal/http-server/handlers"
+	resp "github.com/zanzhit/flat-seller/internal/lib/api/response"
+	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+)
+
+// Load parses the OpenAPI document and checks that it is valid.
+func Load(ctx context.Context, spec []byte) (*openapi3.T, error) {
+	const op = "middleware.openapi.Load"
+
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return doc, nil
+}
+
+// New rejects requests that do not match their operation in the document with
+// 400. Requests to paths missing from the document are passed through, so chi
+// answers them. Authentication is left to the auth middleware.
+//
+// With validateResponses the responses are checked too; mismatches are only
+// logged, since the response has already been sent.
+func New(log *slog.Logger, doc *openapi3.T, validateResponses bool) (func(next http.Handler) http.Handler, error) {
+	const op = "middleware.openapi.New"
+
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log = log.With(slog.String("component", "middleware/openapi"))
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+	}
+	// The default message dumps the whole schema and value into the problem detail.
+	options.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		if pointer := err.JSONPointer(); len(pointer) > 0 {
+			return fmt.Sprintf("field %s: %s", strings.Join(pointer, "."), err.Reason)
+		}
+
+		return err.Reason
+	})
+
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    options,
+			}
+
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				log.Info("request does not match the api",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					sl.Err(err),
+				)
+
+				problem := resp.Error(err.Error())
+				problem.Code = resp.CodeValidation
+
+				handlers.Error(w, r, http.StatusBadRequest, problem)
+
+				return
+			}
+
+			if !validateResponses {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			var body bytes.Buffer
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&body)
+
+			next.ServeHTTP(ww, r)
+
+			validateResponse(log, r, route, input, ww, body.Bytes())
+		}
+
+		return http.HandlerFunc(fn)
+	}, nil
+}
+
+func validateResponse(
+	log *slog.Logger,
+	r *http.Request,
+	route *routers.Route,
+	input *openapi3filter.RequestValidationInput,
+	ww middleware.WrapResponseWriter,
+	body []byte,
+) {
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	responseInput := &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 status,
+		Header:                 ww.Header(),
+		Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+	}
+	responseInput.SetBodyBytes(body)
+
+	if err := openapi3filter.ValidateResponse(r.Context(), responseInput); err != nil {
+		log.Error("response does not match the api",
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("route", r.Method+" "+route.Path),
+			slog.Int("status", status),
+			sl.Err(err),
+		)
+	}
+}
+
+// CheckRoutes returns an error listing the routes registered in chi that are
+// missing from the document and the operations of the document without a route.
+func CheckRoutes(doc *openapi3.T, routes chi.Routes) error {
+	const op = "middleware.openapi.CheckRoutes"
+
+	documented := make(map[string]bool)
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			documented[method+" "+path] = false
+		}
+	}
+
+	var undocumented []string
+
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + route
+		if _, ok := documented[key]; !ok {
+			undocumented = append(undocumented, key)
+
+			return nil
+		}
+
+		documented[key] = true
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var unrouted []string
+	for key, routed := range documented {
+		if !routed {
+			unrouted = append(unrouted, key)
+		}
+	}
+
+	sort.Strings(undocumented)
+	sort.Strings(unrouted)
+
+	var errs []error
+	for _, key := range undocumented {
+		errs = append(errs, fmt.Errorf("route %s is not in the api", key))
+	}
+	for _, key := range unrouted {
+		errs = append(errs, fmt.Errorf("operation %s has no route", key))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -7,25 +7,49 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-type Response struct {
-	Error     string `json:"error,omitempty"`
+// ContentType is the media type of error bodies.
+const ContentType = "application/problem+json"
+
+// Stable codes of errors that do not come from the domain.
+const (
+	CodeBadRequest   = "bad_request"
+	CodeValidation   = "validation_failed"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+	CodeNotFound     = "not_found"
+	CodeConflict     = "conflict"
+	CodeInternal     = "internal_error"
+)
+
+// Problem is an RFC 7807 error body extended with a machine-readable code and
+// the id of the request.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
 	RequestID string `json:"request_id,omitempty"`
 }
 
-func Error(msg, requestID string) Response {
-	return Response{
-		Error:     msg,
-		RequestID: requestID,
+// Error returns a problem with the given detail. The status, title, code and request
+// id are filled in when the problem is written.
+func Error(detail string) Problem {
+	return Problem{
+		Detail: detail,
 	}
 }
 
-func ValidationError(errs validator.ValidationErrors) Response {
+func ValidationError(errs validator.ValidationErrors) Problem {
 	var errMsgs []string
 
 	for _, err := range errs {
 		switch err.ActualTag() {
 		case "required":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
+		case "required_without":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s is required when %s is empty", err.Field(), err.Param()))
 		case "email":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid email address", err.Field()))
 		case "password":
@@ -34,12 +58,15 @@ func ValidationError(errs validator.ValidationErrors) Response {
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid user type", err.Field()))
 		case "id":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid ID", err.Field()))
+		case "price", "rooms", "year":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s is out of the allowed range", err.Field()))
 		default:
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
 		}
 	}
 
-	return Response{
-		Error: strings.Join(errMsgs, ", "),
+	return Problem{
+		Detail: strings.Join(errMsgs, ", "),
+		Code:   CodeValidation,
 	}
 }
@@ -0,0 +1,56 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+)
+
+func Encode(c models.FlatCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func Decode(s string) (models.FlatCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return models.FlatCursor{}, err
+	}
+
+	var c models.FlatCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return models.FlatCursor{}, err
+	}
+
+	return c, nil
+}
+
+// Check reports whether the cursor was issued for the sort and its value fits the
+// sort column, so a cursor is never compared with values of another column.
+func Check(c models.FlatCursor, sortBy string, desc bool) error {
+	if c.SortBy != sortBy || c.Desc != desc {
+		return fmt.Errorf("%w: it does not match sort_by and order", errs.ErrCursor)
+	}
+
+	var err error
+	switch sortBy {
+	case models.SortByCreatedAt:
+		_, err = time.Parse(time.RFC3339Nano, c.Value)
+	default:
+		_, err = strconv.Atoi(c.Value)
+	}
+	if err != nil {
+		return errs.ErrCursor
+	}
+
+	return nil
+}
@@ -7,13 +7,15 @@ import (
 	"github.com/zanzhit/flat-seller/internal/domain/models"
 )
 
-func NewToken(user models.User, duration time.Duration, secret string) (string, error) {
+func NewToken(user models.User, jti string, duration time.Duration, secret string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
+	claims["jti"] = jti
 	claims["uid"] = user.Id
 	claims["email"] = user.Email
 	claims["exp"] = time.Now().Add(duration).Unix()
+	claims["iat"] = IssuedAt(time.Now())
 	claims["user_type"] = user.UserType
 
 	tokenString, err := token.SignedString([]byte(secret))
@@ -23,3 +25,9 @@ func NewToken(user models.User, duration time.Duration, secret string) (string,
 
 	return tokenString, nil
 }
+
+// IssuedAt returns the iat claim for a token issued at t. It keeps milliseconds, so
+// a token issued right after the tokens of the user were revoked stays valid.
+func IssuedAt(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "flat_seller"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requestDuration *prometheus.HistogramVec
+	requestErrors   *prometheus.CounterVec
+
+	flatsCreated    prometheus.Counter
+	flatsModerated  *prometheus.CounterVec
+	usersRegistered *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by route pattern.",
+			Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"method", "route", "status"}),
+		requestErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_request_errors_total",
+			Help:      "Number of HTTP requests answered with 4xx or 5xx status.",
+		}, []string{"method", "route", "status"}),
+		flatsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "flats_created_total",
+			Help:      "Number of created flats.",
+		}),
+		flatsModerated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "flats_moderated_total",
+			Help:      "Number of flat moderation decisions by resulting status.",
+		}, []string{"status"}),
+		usersRegistered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "users_registered_total",
+			Help:      "Number of registered users by user type.",
+		}, []string{"user_type"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestDuration,
+		m.requestErrors,
+		m.flatsCreated,
+		m.flatsModerated,
+		m.usersRegistered,
+	)
+
+	return m
+}
+
+// RegisterDB exposes connection pool stats of the database.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+
+	m.requestDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+
+	if status >= http.StatusBadRequest {
+		m.requestErrors.WithLabelValues(method, route, code).Inc()
+	}
+}
+
+func (m *Metrics) FlatCreated() {
+	m.flatsCreated.Inc()
+}
+
+func (m *Metrics) FlatModerated(status string) {
+	m.flatsModerated.WithLabelValues(status).Inc()
+}
+
+func (m *Metrics) UserRegistered(userType string) {
+	m.usersRegistered.WithLabelValues(userType).Inc()
+}
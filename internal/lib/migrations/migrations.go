@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const upSuffix = ".up.sql"
+
+// LatestVersion returns the highest version among the up migrations in the directory.
+// Migration files are named <version>_<name>.up.sql.
+func LatestVersion(path string) (uint, error) {
+	const op = "lib.migrations.LatestVersion"
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, upSuffix) {
+			continue
+		}
+
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			continue
+		}
+
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,72 @@
+package sender
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink is a local stand-in for the mail provider. Every message is logged and,
+// if a path is configured, appended to a file.
+type Sink struct {
+	log  *slog.Logger
+	mu   sync.Mutex
+	file *os.File
+}
+
+func New(log *slog.Logger, path string) (*Sink, error) {
+	const op = "lib.sender.New"
+
+	s := &Sink{log: log}
+
+	if path == "" {
+		return s, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.file = file
+
+	return s, nil
+}
+
+func (s *Sink) SendEmail(ctx context.Context, recipient, message string) error {
+	const op = "lib.sender.SendEmail"
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.log.Info("sending email",
+		slog.String("op", op),
+		slog.String("recipient", recipient),
+		slog.String("message", message),
+	)
+
+	if s.file == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := fmt.Fprintf(s.file, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), recipient, message); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Sink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+
+	return s.file.Close()
+}
@@ -1,12 +1,16 @@
 package authservice
 
 import (
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/zanzhit/flat-seller/internal/domain/constants"
@@ -14,37 +18,82 @@ import (
 	"github.com/zanzhit/flat-seller/internal/domain/models"
 	jwtmid "github.com/zanzhit/flat-seller/internal/lib/jwt"
 	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+	"github.com/zanzhit/flat-seller/internal/lib/random"
 )
 
+var tracer = otel.Tracer("github.com/zanzhit/flat-seller/internal/services/auth")
+
+const (
+	jtiLength          = 16
+	refreshTokenLength = 32
+)
+
+// dummyHash is checked against the password when the user does not exist.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
 type AuthService struct {
-	secret       string
-	tokenTTL     time.Duration
-	log          *slog.Logger
-	userSaver    UserSaver
-	userProvider UserProvider
+	secret          string
+	tokenTTL        time.Duration
+	refreshTokenTTL time.Duration
+	log             *slog.Logger
+	userSaver       UserSaver
+	userProvider    UserProvider
+	tokenStorage    TokenStorage
+	metrics         Metrics
 }
 
-func New(log *slog.Logger, userSaver UserSaver, userProvider UserProvider, tokenTTL time.Duration, secret string) *AuthService {
+func New(
+	log *slog.Logger,
+	userSaver UserSaver,
+	userProvider UserProvider,
+	tokenStorage TokenStorage,
+	metrics Metrics,
+	tokenTTL time.Duration,
+	refreshTokenTTL time.Duration,
+	secret string,
+) *AuthService {
 	return &AuthService{
-		secret:       secret,
-		tokenTTL:     tokenTTL,
-		log:          log,
-		userSaver:    userSaver,
-		userProvider: userProvider,
+		secret:          secret,
+		tokenTTL:        tokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+		log:             log,
+		userSaver:       userSaver,
+		userProvider:    userProvider,
+		tokenStorage:    tokenStorage,
+		metrics:         metrics,
 	}
 }
 
 type UserSaver interface {
-	SaveUser(email, userType string, passHash []byte) (string, error)
+	SaveUser(ctx context.Context, email, userType string, passHash []byte) (string, error)
 }
 
 type UserProvider interface {
-	User(userID string) (models.User, error)
+	User(ctx context.Context, userID string) (models.User, error)
+	UserByEmail(ctx context.Context, email string) (models.User, error)
 }
 
-func (s *AuthService) RegisterNewUser(email, password, userType string) (string, error) {
+type TokenStorage interface {
+	SaveRefreshToken(ctx context.Context, tokenHash []byte, token models.RefreshToken) error
+	TakeRefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error)
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID string, expiresAt time.Time) error
+}
+
+type Metrics interface {
+	UserRegistered(userType string)
+}
+
+// RegisterNewUser saves the user with the email in lower case, so it is unique
+// regardless of case.
+func (s *AuthService) RegisterNewUser(ctx context.Context, email, password, userType string) (string, error) {
 	const op = "service.auth.Register"
 
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	email = strings.ToLower(email)
+
 	log := s.log.With(
 		slog.String("op", op),
 		slog.String("email", email),
@@ -64,60 +113,238 @@ func (s *AuthService) RegisterNewUser(email, password, userType string) (string,
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	id, err := s.userSaver.SaveUser(email, userType, passHash)
+	id, err := s.userSaver.SaveUser(ctx, email, userType, passHash)
 	if err != nil {
 		log.Error("failed to save user", sl.Err(err))
 
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.metrics.UserRegistered(userType)
+
 	return id, nil
 }
 
-func (s *AuthService) Login(userID, password string) (string, error) {
+// Login checks the password of the user found by email in any case, or by id if the
+// email is empty.
+// An unknown user costs the same password check as a wrong password, so the response
+// time does not reveal which accounts exist.
+func (s *AuthService) Login(ctx context.Context, userID, email, password string) (models.Tokens, error) {
 	const op = "service.auth.Login"
 
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	email = strings.ToLower(email)
+
 	log := s.log.With(
 		slog.String("op", op),
 		slog.String("userID", userID),
+		slog.String("email", email),
 	)
 
 	log.Info("attempting to login user")
 
-	user, err := s.userProvider.User(userID)
+	var user models.User
+	var err error
+	if email != "" {
+		user, err = s.userProvider.UserByEmail(ctx, email)
+	} else {
+		user, err = s.userProvider.User(ctx, userID)
+	}
 	if err != nil {
 		if errors.Is(err, errs.ErrInvalidCredentials) {
 			s.log.Warn("user not found", sl.Err(err))
 
-			return "", fmt.Errorf("%s: %w", op, errs.ErrInvalidCredentials)
+			_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, errs.ErrInvalidCredentials)
 		}
 
 		s.log.Error("failed to get user", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
 		s.log.Info("invalid credentials", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, errs.ErrInvalidCredentials)
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, errs.ErrInvalidCredentials)
 	}
 
 	log.Info("user logged in successfully")
 
-	token, err := jwtmid.NewToken(user, s.tokenTTL, s.secret)
+	tokens, err := s.issueTokens(ctx, user)
 	if err != nil {
-		s.log.Error("failed to generate token", sl.Err(err))
+		s.log.Error("failed to generate tokens", sl.Err(err))
 
-		return "", fmt.Errorf("%s: %w", op, err)
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The used refresh token
+// is consumed and the access token issued together with it is revoked.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (models.Tokens, error) {
+	const op = "service.auth.Refresh"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	hash := sha256.Sum256([]byte(refreshToken))
+
+	stored, err := s.tokenStorage.TakeRefreshToken(ctx, hash[:])
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidToken) {
+			log.Warn("refresh token not found", sl.Err(err))
+
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, errs.ErrInvalidToken)
+		}
+
+		log.Error("failed to get refresh token", sl.Err(err))
+
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := s.tokenStorage.RevokeToken(ctx, stored.AccessJTI, time.Now().Add(s.tokenTTL)); err != nil {
+		log.Error("failed to revoke access token", sl.Err(err))
+
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		log.Warn("refresh token expired")
+
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, errs.ErrInvalidToken)
+	}
+
+	user, err := s.userProvider.User(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrInvalidCredentials) {
+			log.Warn("user not found", sl.Err(err))
+
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, errs.ErrInvalidToken)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tokens, err := s.issueTokens(ctx, user)
+	if err != nil {
+		log.Error("failed to generate tokens", sl.Err(err))
+
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return token, nil
+	return tokens, nil
 }
 
-func (s *AuthService) GenerateToken(userID, email, userType string) (string, error) {
+// Logout revokes the access token and every refresh token issued together with it.
+func (s *AuthService) Logout(ctx context.Context, jti string, expiresAt time.Time) error {
+	const op = "service.auth.Logout"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("jti", jti),
+	)
+
+	if err := s.tokenStorage.RevokeToken(ctx, jti, expiresAt); err != nil {
+		log.Error("failed to revoke token", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user logged out")
+
+	return nil
+}
+
+// RevokeUser signs the user out everywhere: every access token of the user issued
+// until now is revoked and the refresh tokens are removed, so the user has to log in again.
+func (s *AuthService) RevokeUser(ctx context.Context, userID string) error {
+	const op = "service.auth.RevokeUser"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("userID", userID),
+	)
+
+	if _, err := s.userProvider.User(ctx, userID); err != nil {
+		if errors.Is(err, errs.ErrInvalidCredentials) {
+			log.Warn("user not found", sl.Err(err))
+
+			return fmt.Errorf("%s: %w", op, errs.ErrUserNotFound)
+		}
+
+		log.Error("failed to get user", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Access tokens issued before now expire within the token ttl, so the denylist
+	// entries can be dropped after it.
+	if err := s.tokenStorage.RevokeUserTokens(ctx, userID, time.Now().Add(s.tokenTTL)); err != nil {
+		log.Error("failed to revoke user tokens", sl.Err(err))
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("user tokens revoked")
+
+	return nil
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user models.User) (models.Tokens, error) {
+	jti, err := random.String(jtiLength)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	accessToken, err := jwtmid.NewToken(user, jti, s.tokenTTL, s.secret)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	refreshToken, err := random.String(refreshTokenLength)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	hash := sha256.Sum256([]byte(refreshToken))
+	err = s.tokenStorage.SaveRefreshToken(ctx, hash[:], models.RefreshToken{
+		UserID:    user.Id,
+		AccessJTI: jti,
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	})
+	if err != nil {
+		return models.Tokens{}, err
+	}
+
+	return models.Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (s *AuthService) GenerateToken(ctx context.Context, userID, email, userType string) (string, error) {
 	const op = "service.auth.GenerateToken"
 
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
 	log := s.log.With(
 		slog.String("op", op),
 		slog.String("userID", userID),
@@ -125,11 +352,19 @@ func (s *AuthService) GenerateToken(userID, email, userType string) (string, err
 		slog.String("userType", userType),
 	)
 
+	jti, err := random.String(jtiLength)
+	if err != nil {
+		log.Error("failed to generate token id", sl.Err(err))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
 	claims := jwt.MapClaims{
+		"jti":       jti,
 		"uid":       userID,
 		"email":     email,
 		"user_type": userType,
 		"exp":       time.Now().Add(s.tokenTTL).Unix(),
+		"iat":       jwtmid.IssuedAt(time.Now()),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -1,8 +1,13 @@
 package flatservice
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel"
 
 	"github.com/zanzhit/flat-seller/internal/domain/constants"
 	"github.com/zanzhit/flat-seller/internal/domain/errs"
@@ -10,60 +15,349 @@ import (
 	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
 )
 
+var tracer = otel.Tracer("github.com/zanzhit/flat-seller/internal/services/flat")
+
 type FlatService struct {
-	log  *slog.Logger
-	flat Flat
+	log      *slog.Logger
+	flat     Flat
+	notifier Notifier
+	metrics  Metrics
+	lockTTL  time.Duration
 }
 
-func New(log *slog.Logger, flat Flat) *FlatService {
+func New(log *slog.Logger, flat Flat, notifier Notifier, metrics Metrics, lockTTL time.Duration) *FlatService {
 	return &FlatService{
-		log:  log,
-		flat: flat,
+		log:      log,
+		flat:     flat,
+		notifier: notifier,
+		metrics:  metrics,
+		lockTTL:  lockTTL,
 	}
 }
 
 type Flat interface {
-	SaveFlat(houseID, price, rooms int) (models.Flat, error)
-	UpdateFlat(flatID, price, rooms int, status string) (models.Flat, error)
+	SaveFlat(ctx context.Context, ownerID string, houseID, price, rooms int) (models.Flat, error)
+	UpdateFlat(ctx context.Context, flat models.Flat, moderatorID, prevStatus string, lockExpiredBefore time.Time) (models.Flat, error)
+	Flat(ctx context.Context, flatID int) (models.Flat, error)
+	Queue(ctx context.Context, limit, offset int, lockExpiredBefore time.Time) ([]models.Flat, error)
+	TakeNext(ctx context.Context, moderatorID string, lockExpiredBefore time.Time) (models.Flat, error)
+	OwnerFlats(ctx context.Context, ownerID string) ([]models.Flat, error)
+	UpdateOwnerFlat(ctx context.Context, flat models.Flat, ownerID, prevStatus string) (models.Flat, error)
 }
 
-func (s *FlatService) SaveFlat(houseID, price, rooms int) (models.Flat, error) {
+type Notifier interface {
+	FlatApproved(ctx context.Context, flat models.Flat)
+}
+
+type Metrics interface {
+	FlatCreated()
+	FlatModerated(status string)
+}
+
+func (s *FlatService) SaveFlat(ctx context.Context, ownerID string, houseID, price, rooms int) (models.Flat, error) {
 	const op = "service.flat.SaveFlat"
 
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
 	log := s.log.With(
 		slog.String("op", op),
 		slog.Int("house_id", houseID),
+		slog.String("owner_id", ownerID),
 	)
 
 	log.Info("saving flat")
 
-	flat, err := s.flat.SaveFlat(houseID, price, rooms)
+	flat, err := s.flat.SaveFlat(ctx, ownerID, houseID, price, rooms)
 	if err != nil {
 		log.Error("failed to save flat", sl.Err(err))
 
 		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
+	s.metrics.FlatCreated()
+
 	return flat, nil
 }
 
-func (s *FlatService) UpdateFlat(flatID, price, rooms int, status string) (models.Flat, error) {
+// UpdateFlat changes the flat on behalf of the moderator. Moving a flat on moderation
+// locks it for the moderator until the lock expires; while the lock is held nobody else
+// can change the flat, and after it expires the flat is treated as created again.
+// The stored status of a flat with an expired lock stays on moderation until another
+// moderator takes it, so listings keep showing it as on moderation meanwhile.
+// A declined flat must carry a decline reason; the reason is cleared on any other status.
+func (s *FlatService) UpdateFlat(ctx context.Context, moderatorID string, flatID, price, rooms int, status string, reason models.DeclineReason) (models.Flat, error) {
 	const op = "service.flat.UpdateFlat"
 
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
 	log := s.log.With(
 		slog.String("op", op),
 		slog.Int("flat_id", flatID),
+		slog.String("moderator_id", moderatorID),
 	)
 
-	if status != constants.Declined && status != constants.Approved && status != constants.Moderation && status != constants.Created {
+	if !isValidStatus(status) {
 		log.Warn("invalid status", sl.Err(errs.ErrFlatStatus))
 
 		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatStatus)
 	}
 
+	if status == constants.Declined && !isValidDeclineReason(reason) {
+		log.Warn("invalid decline reason", slog.String("reason", reason.Code), sl.Err(errs.ErrDeclineReason))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrDeclineReason)
+	}
+
+	current, err := s.flat.Flat(ctx, flatID)
+	if err != nil {
+		log.Error("failed to get flat", sl.Err(err))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	now := time.Now()
+	lockExpiredBefore := now.Add(-s.lockTTL)
+
+	from := current.Status
+	if from == constants.Moderation && !lockExpired(current, lockExpiredBefore) && !current.ModeratedBy(moderatorID) {
+		log.Warn("flat is locked by another moderator", sl.Err(errs.ErrFlatLocked))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatLocked)
+	}
+	if from == constants.Moderation && lockExpired(current, lockExpiredBefore) {
+		from = constants.Created
+	}
+
+	if !canTransition(from, status) {
+		log.Warn("invalid status transition",
+			slog.String("from", from),
+			slog.String("to", status),
+			sl.Err(errs.ErrFlatTransition),
+		)
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatTransition)
+	}
+
+	log.Info("updating flat")
+
+	update := models.Flat{
+		ID:     flatID,
+		Price:  price,
+		Rooms:  rooms,
+		Status: status,
+	}
+	// The moderator stays on approved and declined flats as the one who decided;
+	// only a flat on moderation is locked.
+	if status != constants.Created {
+		update.ModeratorID = &moderatorID
+	}
+	if status == constants.Moderation {
+		update.ModerationStartedAt = &now
+	}
+	if status == constants.Declined {
+		update.DeclineReason = &reason.Code
+		if reason.Comment != "" {
+			update.DeclineComment = &reason.Comment
+		}
+	}
+
+	flat, err := s.flat.UpdateFlat(ctx, update, moderatorID, current.Status, lockExpiredBefore)
+	if err != nil {
+		log.Error("failed to update flat", sl.Err(err))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Corrections that keep the status are neither counted nor announced again.
+	if flat.Status == current.Status {
+		return flat, nil
+	}
+
+	if flat.Status == constants.Approved || flat.Status == constants.Declined {
+		s.metrics.FlatModerated(flat.Status)
+	}
+
+	if flat.Status == constants.Approved {
+		s.notifier.FlatApproved(ctx, flat)
+	}
+
+	return flat, nil
+}
+
+// Flat returns the flat as the user may see it: moderators see every flat, clients
+// see approved flats and their own ones. Hidden flats are reported as not found,
+// so clients cannot tell them from missing ones.
+func (s *FlatService) Flat(ctx context.Context, userID, userType string, flatID int) (models.Flat, error) {
+	const op = "service.flat.Flat"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("flat_id", flatID),
+		slog.String("user_id", userID),
+	)
+
+	flat, err := s.flat.Flat(ctx, flatID)
+	if err != nil {
+		if errors.Is(err, errs.ErrFlatNotFound) {
+			log.Info("flat not found")
+
+			return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to get flat", sl.Err(err))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if userType != constants.Admin && flat.Status != constants.Approved && !flat.OwnedBy(userID) {
+		log.Info("flat is hidden from the user", slog.String("status", flat.Status))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatNotFound)
+	}
+
+	return flat, nil
+}
+
+func (s *FlatService) Queue(ctx context.Context, limit, offset int) ([]models.Flat, error) {
+	const op = "service.flat.Queue"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	log := s.log.With(
+		slog.String("op", op),
+	)
+
+	flats, err := s.flat.Queue(ctx, limit, offset, time.Now().Add(-s.lockTTL))
+	if err != nil {
+		log.Error("failed to get moderation queue", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+func (s *FlatService) TakeNext(ctx context.Context, moderatorID string) (models.Flat, error) {
+	const op = "service.flat.TakeNext"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("moderator_id", moderatorID),
+	)
+
+	flat, err := s.flat.TakeNext(ctx, moderatorID, time.Now().Add(-s.lockTTL))
+	if err != nil {
+		if errors.Is(err, errs.ErrQueueEmpty) {
+			log.Info("moderation queue is empty")
+
+			return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		log.Error("failed to take flat on moderation", sl.Err(err))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("flat taken on moderation", slog.Int("flat_id", flat.ID))
+
+	return flat, nil
+}
+
+func (s *FlatService) OwnerFlats(ctx context.Context, ownerID string) ([]models.Flat, error) {
+	const op = "service.flat.OwnerFlats"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	log := s.log.With(
+		slog.String("op", op),
+		slog.String("owner_id", ownerID),
+	)
+
+	flats, err := s.flat.OwnerFlats(ctx, ownerID)
+	if err != nil {
+		log.Error("failed to get owner flats", sl.Err(err))
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+// EditFlat changes the price and rooms of the owner's flat and sends it back to moderation.
+func (s *FlatService) EditFlat(ctx context.Context, ownerID string, flatID, price, rooms int) (models.Flat, error) {
+	const op = "service.flat.EditFlat"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	return s.updateOwnerFlat(ctx, op, ownerID, flatID, constants.Created, func(flat *models.Flat) {
+		flat.Price = price
+		flat.Rooms = rooms
+	})
+}
+
+// WithdrawFlat removes the owner's flat from sale.
+func (s *FlatService) WithdrawFlat(ctx context.Context, ownerID string, flatID int) (models.Flat, error) {
+	const op = "service.flat.WithdrawFlat"
+
+	ctx, span := tracer.Start(ctx, op)
+	defer span.End()
+
+	return s.updateOwnerFlat(ctx, op, ownerID, flatID, constants.Withdrawn, func(*models.Flat) {})
+}
+
+func (s *FlatService) updateOwnerFlat(ctx context.Context, op, ownerID string, flatID int, status string, change func(*models.Flat)) (models.Flat, error) {
+	log := s.log.With(
+		slog.String("op", op),
+		slog.Int("flat_id", flatID),
+		slog.String("owner_id", ownerID),
+	)
+
+	current, err := s.flat.Flat(ctx, flatID)
+	if err != nil {
+		log.Error("failed to get flat", sl.Err(err))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !current.OwnedBy(ownerID) {
+		log.Warn("flat belongs to another user", sl.Err(errs.ErrFlatOwner))
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatOwner)
+	}
+
+	from := current.Status
+	if from == constants.Moderation && lockExpired(current, time.Now().Add(-s.lockTTL)) {
+		from = constants.Created
+	}
+
+	if !canOwnerTransition(from, status) {
+		log.Warn("invalid status transition",
+			slog.String("from", from),
+			slog.String("to", status),
+			sl.Err(errs.ErrFlatTransition),
+		)
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatTransition)
+	}
+
+	update := current
+	update.Status = status
+	change(&update)
+
 	log.Info("updating flat")
 
-	flat, err := s.flat.UpdateFlat(flatID, price, rooms, status)
+	flat, err := s.flat.UpdateOwnerFlat(ctx, update, ownerID, current.Status)
 	if err != nil {
 		log.Error("failed to update flat", sl.Err(err))
 
@@ -72,3 +366,18 @@ func (s *FlatService) UpdateFlat(flatID, price, rooms int, status string) (model
 
 	return flat, nil
 }
+
+func lockExpired(flat models.Flat, expiredBefore time.Time) bool {
+	return flat.ModerationStartedAt == nil || flat.ModerationStartedAt.Before(expiredBefore)
+}
+
+func isValidDeclineReason(reason models.DeclineReason) bool {
+	switch reason.Code {
+	case constants.ReasonIncorrectPrice, constants.ReasonIncorrectData, constants.ReasonDuplicate, constants.ReasonProhibitedContent:
+		return true
+	case constants.ReasonOther:
+		return reason.Comment != ""
+	default:
+		return false
+	}
+}
@@ -0,0 +1,48 @@
+package flatservice
+
+import "github.com/zanzhit/flat-seller/internal/domain/constants"
+
+// transitions lists the statuses a flat can be moved to from each status.
+// A declined flat can be taken on moderation again once the seller has fixed it.
+// Keeping the status lets the moderator correct the price and rooms of a moderated flat.
+var transitions = map[string][]string{
+	constants.Created:    {constants.Moderation},
+	constants.Moderation: {constants.Moderation, constants.Approved, constants.Declined, constants.Created},
+	constants.Declined:   {constants.Declined, constants.Moderation},
+	constants.Approved:   {constants.Approved},
+	constants.Withdrawn:  {},
+}
+
+// ownerTransitions lists the statuses the seller can move their own flat to.
+// Editing a flat sends it back to created, so it goes through moderation again.
+var ownerTransitions = map[string][]string{
+	constants.Created:    {constants.Created, constants.Withdrawn},
+	constants.Moderation: {constants.Withdrawn},
+	constants.Approved:   {constants.Created, constants.Withdrawn},
+	constants.Declined:   {constants.Created, constants.Withdrawn},
+	constants.Withdrawn:  {constants.Created},
+}
+
+func isValidStatus(status string) bool {
+	_, ok := transitions[status]
+
+	return ok
+}
+
+func canTransition(from, to string) bool {
+	return contains(transitions[from], to)
+}
+
+func canOwnerTransition(from, to string) bool {
+	return contains(ownerTransitions[from], to)
+}
+
+func contains(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
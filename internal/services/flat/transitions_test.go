@@ -0,0 +1,307 @@
+package flatservice_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	flatservice "github.com/zanzhit/flat-seller/internal/services/flat"
+	memstorage "github.com/zanzhit/flat-seller/internal/storage/memory"
+)
+
+const lockTTL = time.Hour
+
+var statuses = []string{
+	constants.Created,
+	constants.Moderation,
+	constants.Approved,
+	constants.Declined,
+	constants.Withdrawn,
+}
+
+type notifier struct{}
+
+func (notifier) FlatApproved(context.Context, models.Flat) {}
+
+type metrics struct{}
+
+func (metrics) FlatCreated() {}
+
+func (metrics) FlatModerated(string) {}
+
+type fixture struct {
+	storage   *memstorage.Storage
+	service   *flatservice.FlatService
+	ownerID   string
+	moderator string
+	houseID   int
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+
+	ctx := context.Background()
+	storage := memstorage.New()
+
+	ownerID, err := storage.SaveUser(ctx, "owner@example.com", constants.User, []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moderatorID, err := storage.SaveUser(ctx, "moderator@example.com", constants.Admin, []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	house, err := storage.SaveHouse(ctx, "Lenina 1", "Developer", 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return fixture{
+		storage:   storage,
+		service:   flatservice.New(log, storage, notifier{}, metrics{}, lockTTL),
+		ownerID:   ownerID,
+		moderator: moderatorID,
+		houseID:   house.ID,
+	}
+}
+
+// flatIn saves a flat of the owner and moves it to the status, locked by the moderator
+// when it is on moderation.
+func (f fixture) flatIn(t *testing.T, status string) models.Flat {
+	t.Helper()
+
+	return f.flatSince(t, status, time.Now())
+}
+
+// flatSince is flatIn with the moderation started at startedAt.
+func (f fixture) flatSince(t *testing.T, status string, startedAt time.Time) models.Flat {
+	t.Helper()
+
+	ctx := context.Background()
+
+	flat, err := f.storage.SaveFlat(ctx, f.ownerID, f.houseID, 1000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status == constants.Created {
+		return flat
+	}
+
+	flat.Status = status
+	flat.ModeratorID = &f.moderator
+	flat.ModerationStartedAt = &startedAt
+	if status == constants.Declined {
+		reason := constants.ReasonDuplicate
+		flat.DeclineReason = &reason
+	}
+
+	flat, err = f.storage.UpdateFlat(ctx, flat, f.moderator, constants.Created, time.Now().Add(-lockTTL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return flat
+}
+
+func TestModeratorTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		err  error
+	}{
+		{"created is taken on moderation", constants.Created, constants.Moderation, nil},
+		{"created cannot be approved unseen", constants.Created, constants.Approved, errs.ErrFlatTransition},
+		{"created cannot be declined unseen", constants.Created, constants.Declined, errs.ErrFlatTransition},
+		{"moderation is corrected", constants.Moderation, constants.Moderation, nil},
+		{"moderation is approved", constants.Moderation, constants.Approved, nil},
+		{"moderation is declined", constants.Moderation, constants.Declined, nil},
+		{"moderation is released", constants.Moderation, constants.Created, nil},
+		{"moderation cannot be withdrawn by a moderator", constants.Moderation, constants.Withdrawn, errs.ErrFlatTransition},
+		{"declined is corrected", constants.Declined, constants.Declined, nil},
+		{"declined is reconsidered", constants.Declined, constants.Moderation, nil},
+		{"declined cannot go back to created", constants.Declined, constants.Created, errs.ErrFlatTransition},
+		{"declined cannot be approved without moderation", constants.Declined, constants.Approved, errs.ErrFlatTransition},
+		{"approved is corrected", constants.Approved, constants.Approved, nil},
+		{"approved cannot go back to created", constants.Approved, constants.Created, errs.ErrFlatTransition},
+		{"approved cannot go back on moderation", constants.Approved, constants.Moderation, errs.ErrFlatTransition},
+		{"approved cannot be declined", constants.Approved, constants.Declined, errs.ErrFlatTransition},
+		{"withdrawn cannot be taken on moderation", constants.Withdrawn, constants.Moderation, errs.ErrFlatTransition},
+		{"withdrawn cannot be approved", constants.Withdrawn, constants.Approved, errs.ErrFlatTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			flat := f.flatIn(t, tt.from)
+
+			reason := models.DeclineReason{Code: constants.ReasonIncorrectPrice}
+			updated, err := f.service.UpdateFlat(context.Background(), f.moderator, flat.ID, 2000, 3, tt.to, reason)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected transition to be allowed, got %v", err)
+			}
+			if updated.Status != tt.to || updated.Price != 2000 || updated.Rooms != 3 {
+				t.Fatalf("unexpected flat after update: %+v", updated)
+			}
+			if moderated := updated.ModeratedBy(f.moderator); moderated != (tt.to != constants.Created) {
+				t.Fatalf("expected the moderator to stay on the flat only when it is not released, got %+v", updated)
+			}
+		})
+	}
+}
+
+func TestOwnerTransitions(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		err  error
+	}{
+		{"created is edited", constants.Created, constants.Created, nil},
+		{"created is withdrawn", constants.Created, constants.Withdrawn, nil},
+		{"moderation cannot be edited", constants.Moderation, constants.Created, errs.ErrFlatTransition},
+		{"moderation is withdrawn", constants.Moderation, constants.Withdrawn, nil},
+		{"approved is edited", constants.Approved, constants.Created, nil},
+		{"approved is withdrawn", constants.Approved, constants.Withdrawn, nil},
+		{"declined is edited", constants.Declined, constants.Created, nil},
+		{"declined is withdrawn", constants.Declined, constants.Withdrawn, nil},
+		{"withdrawn is edited", constants.Withdrawn, constants.Created, nil},
+		{"withdrawn cannot be withdrawn again", constants.Withdrawn, constants.Withdrawn, errs.ErrFlatTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			flat := f.flatIn(t, tt.from)
+
+			var updated models.Flat
+			var err error
+			if tt.to == constants.Created {
+				updated, err = f.service.EditFlat(context.Background(), f.ownerID, flat.ID, 2000, 3)
+			} else {
+				updated, err = f.service.WithdrawFlat(context.Background(), f.ownerID, flat.ID)
+			}
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected transition to be allowed, got %v", err)
+			}
+			if updated.Status != tt.to || updated.ModeratorID != nil || updated.DeclineReason != nil {
+				t.Fatalf("unexpected flat after update: %+v", updated)
+			}
+		})
+	}
+}
+
+func TestCorrectionRenewsLock(t *testing.T) {
+	f := newFixture(t)
+	startedAt := time.Now().Add(-lockTTL / 2)
+	flat := f.flatSince(t, constants.Moderation, startedAt)
+
+	updated, err := f.service.UpdateFlat(context.Background(), f.moderator, flat.ID, 2000, 3, constants.Moderation, models.DeclineReason{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.ModerationStartedAt == nil || !updated.ModerationStartedAt.After(startedAt) {
+		t.Fatalf("expected the lock to be renewed after %v, got %v", startedAt, updated.ModerationStartedAt)
+	}
+}
+
+func TestExpiredLockIsTakenOver(t *testing.T) {
+	f := newFixture(t)
+	flat := f.flatSince(t, constants.Moderation, time.Now().Add(-2*lockTTL))
+
+	// The flat is back in the queue, so it has to be taken before a decision.
+	_, err := f.service.UpdateFlat(context.Background(), "another-moderator", flat.ID, 2000, 3, constants.Approved, models.DeclineReason{})
+	if !errors.Is(err, errs.ErrFlatTransition) {
+		t.Fatalf("expected ErrFlatTransition, got %v", err)
+	}
+
+	updated, err := f.service.UpdateFlat(context.Background(), "another-moderator", flat.ID, 2000, 3, constants.Moderation, models.DeclineReason{})
+	if err != nil {
+		t.Fatalf("expected the expired lock to be taken over, got %v", err)
+	}
+	if updated.ModeratorID == nil || *updated.ModeratorID != "another-moderator" {
+		t.Fatalf("expected the flat to be locked by another moderator, got %+v", updated)
+	}
+}
+
+func TestDeclineReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason models.DeclineReason
+		err    error
+	}{
+		{"no reason", models.DeclineReason{}, errs.ErrDeclineReason},
+		{"unknown reason", models.DeclineReason{Code: "ugly"}, errs.ErrDeclineReason},
+		{"other without a comment", models.DeclineReason{Code: constants.ReasonOther}, errs.ErrDeclineReason},
+		{"other with a comment", models.DeclineReason{Code: constants.ReasonOther, Comment: "No windows"}, nil},
+		{"known reason", models.DeclineReason{Code: constants.ReasonDuplicate}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			flat := f.flatIn(t, constants.Moderation)
+
+			updated, err := f.service.UpdateFlat(context.Background(), f.moderator, flat.ID, 2000, 3, constants.Declined, tt.reason)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if err == nil && (updated.DeclineReason == nil || *updated.DeclineReason != tt.reason.Code) {
+				t.Fatalf("expected reason %q, got %+v", tt.reason.Code, updated)
+			}
+		})
+	}
+}
+
+func TestUnknownStatus(t *testing.T) {
+	f := newFixture(t)
+
+	for _, from := range statuses {
+		flat := f.flatIn(t, from)
+
+		_, err := f.service.UpdateFlat(context.Background(), f.moderator, flat.ID, 2000, 3, "sold", models.DeclineReason{})
+		if !errors.Is(err, errs.ErrFlatStatus) {
+			t.Fatalf("%s: expected ErrFlatStatus, got %v", from, err)
+		}
+	}
+}
+
+func TestLockedByAnotherModerator(t *testing.T) {
+	f := newFixture(t)
+	flat := f.flatIn(t, constants.Moderation)
+
+	_, err := f.service.UpdateFlat(context.Background(), "another-moderator", flat.ID, 2000, 3, constants.Approved, models.DeclineReason{})
+	if !errors.Is(err, errs.ErrFlatLocked) {
+		t.Fatalf("expected ErrFlatLocked, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zanzhit/flat-seller/internal/config"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+)
+
+var tracer = otel.Tracer("github.com/zanzhit/flat-seller/internal/services/notifier")
+
+type Notifier struct {
+	log         *slog.Logger
+	sender      Sender
+	subscribers SubscriberProvider
+	workers     int
+	maxRetries  int
+	retryDelay  time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan job
+	wg     sync.WaitGroup
+
+	// ctx is cancelled when Stop gives up waiting, so deliveries and their retries
+	// are abandoned.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func New(log *slog.Logger, sender Sender, subscribers SubscriberProvider, cfg config.Notifier) *Notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Notifier{
+		log:         log,
+		sender:      sender,
+		subscribers: subscribers,
+		workers:     cfg.Workers,
+		maxRetries:  cfg.MaxRetries,
+		retryDelay:  cfg.RetryDelay,
+		queue:       make(chan job, cfg.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// job is a queued notification. It keeps the span of the request that approved
+// the flat, so the delivery can be linked to it without inheriting its deadline.
+type job struct {
+	flat models.Flat
+	link trace.Link
+}
+
+type Sender interface {
+	SendEmail(ctx context.Context, recipient, message string) error
+}
+
+type SubscriberProvider interface {
+	Subscribers(ctx context.Context, houseID int) ([]string, error)
+}
+
+// Start launches the workers that deliver queued notifications.
+func (n *Notifier) Start() {
+	for i := 0; i < n.workers; i++ {
+		n.wg.Add(1)
+
+		go func() {
+			defer n.wg.Done()
+
+			for job := range n.queue {
+				if n.ctx.Err() != nil {
+					n.log.Warn("notifier is stopped, notification dropped", slog.Int("flat_id", job.flat.ID))
+
+					continue
+				}
+
+				n.notify(job)
+			}
+		}()
+	}
+}
+
+// Stop stops accepting new notifications and waits until the queue is drained.
+// When ctx is done first, pending retries and queued notifications are abandoned
+// and ctx.Err() is returned once the workers have exited.
+func (n *Notifier) Stop(ctx context.Context) error {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		n.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		n.cancel()
+
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+		<-drained
+
+		return ctx.Err()
+	}
+}
+
+// FlatApproved enqueues notifications about the flat for every subscriber of its house.
+// It never blocks: if the queue is full the notification is dropped.
+func (n *Notifier) FlatApproved(ctx context.Context, flat models.Flat) {
+	const op = "service.notifier.FlatApproved"
+
+	log := n.log.With(
+		slog.String("op", op),
+		slog.Int("flat_id", flat.ID),
+		slog.Int("house_id", flat.HouseID),
+	)
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if n.closed {
+		log.Warn("notifier is stopped, notification dropped")
+
+		return
+	}
+
+	select {
+	case n.queue <- job{flat: flat, link: trace.LinkFromContext(ctx)}:
+	default:
+		log.Warn("notification queue is full, notification dropped")
+	}
+}
+
+func (n *Notifier) notify(job job) {
+	const op = "service.notifier.notify"
+
+	flat := job.flat
+
+	ctx, span := tracer.Start(n.ctx, op,
+		trace.WithLinks(job.link),
+		trace.WithAttributes(
+			attribute.Int("flat_id", flat.ID),
+			attribute.Int("house_id", flat.HouseID),
+		),
+	)
+	defer span.End()
+
+	log := n.log.With(
+		slog.String("op", op),
+		slog.Int("flat_id", flat.ID),
+		slog.Int("house_id", flat.HouseID),
+	)
+
+	emails, err := n.subscribers.Subscribers(ctx, flat.HouseID)
+	if err != nil {
+		log.Error("failed to get subscribers", sl.Err(err))
+
+		return
+	}
+
+	message := fmt.Sprintf("New flat in house %d: flat number %d, %d rooms, price %d",
+		flat.HouseID, flat.FlatNumber, flat.Rooms, flat.Price)
+
+	for _, email := range emails {
+		if err := n.send(ctx, email, message); err != nil {
+			log.Error("failed to send email", slog.String("email", email), sl.Err(err))
+		}
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, email, message string) error {
+	var err error
+
+	delay := n.retryDelay
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+
+				return errors.Join(err, ctx.Err())
+			}
+
+			delay *= 2
+		}
+
+		if err = n.sender.SendEmail(ctx, email, message); err == nil {
+			return nil
+		}
+
+		n.log.Warn("email sending attempt failed",
+			slog.String("email", email),
+			slog.Int("attempt", attempt+1),
+			sl.Err(err),
+		)
+	}
+
+	return err
+}
@@ -0,0 +1,61 @@
+package notifier_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zanzhit/flat-seller/internal/config"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/services/notifier"
+)
+
+type failingSender struct {
+	attempts atomic.Int32
+}
+
+func (s *failingSender) SendEmail(context.Context, string, string) error {
+	s.attempts.Add(1)
+
+	return errors.New("mail provider is down")
+}
+
+type subscribers []string
+
+func (s subscribers) Subscribers(context.Context, int) ([]string, error) {
+	return s, nil
+}
+
+func TestStopAbandonsRetries(t *testing.T) {
+	sender := &failingSender{}
+	n := notifier.New(slog.New(slog.NewTextHandler(io.Discard, nil)), sender, subscribers{"buyer@example.com"}, config.Notifier{
+		Workers:    1,
+		QueueSize:  10,
+		MaxRetries: 5,
+		RetryDelay: time.Hour,
+	})
+	n.Start()
+
+	n.FlatApproved(context.Background(), models.Flat{ID: 1, HouseID: 1})
+	n.FlatApproved(context.Background(), models.Flat{ID: 2, HouseID: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := n.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected Stop to return at the deadline, took %v", elapsed)
+	}
+
+	// The first notification was attempted once, the queued one was dropped.
+	if attempts := sender.attempts.Load(); attempts != 1 {
+		t.Fatalf("expected a single attempt, got %d", attempts)
+	}
+}
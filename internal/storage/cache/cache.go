@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	househandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/house"
+	"github.com/zanzhit/flat-seller/internal/lib/logger/sl"
+	flatservice "github.com/zanzhit/flat-seller/internal/services/flat"
+)
+
+const (
+	clientView    = "client"
+	moderatorView = "moderator"
+)
+
+// Cache is a cache backend. Backend failures must be reported as misses,
+// so an unavailable external cache only makes requests slower.
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// Listings holds the cached flat listings of houses shared by the House and Flat
+// decorators. Listings are keyed by the generation of their house, and every
+// invalidation bumps it, so stale listings are no longer read and age out of the
+// backend by ttl or eviction. A listing loaded before an invalidation is not written
+// back. Generations are kept in process, so other instances sharing an external
+// backend keep serving their listings until the ttl.
+type Listings struct {
+	cache Cache
+
+	mu          sync.Mutex
+	generations map[int]uint64
+}
+
+func NewListings(cache Cache) *Listings {
+	return &Listings{
+		cache:       cache,
+		generations: make(map[int]uint64),
+	}
+}
+
+func (l *Listings) generation(houseID int) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.generations[houseID]
+}
+
+// set stores the listing unless the house was invalidated after generation was read.
+func (l *Listings) set(houseID int, generation uint64, key string, value []byte, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.generations[houseID] != generation {
+		return
+	}
+
+	l.cache.Set(key, value, ttl)
+}
+
+func (l *Listings) invalidate(houseID int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.generations[houseID]++
+}
+
+// Aliases give the embedded storages field names that do not clash with their methods.
+type (
+	houseStorage = househandler.House
+	flatStorage  = flatservice.Flat
+)
+
+// House caches flat listings of houses. Client and moderator views are cached separately.
+// Changing or deleting a house drops its listings.
+type House struct {
+	houseStorage
+	log      *slog.Logger
+	listings *Listings
+	ttl      time.Duration
+}
+
+func NewHouse(log *slog.Logger, house househandler.House, listings *Listings, ttl time.Duration) *House {
+	return &House{
+		houseStorage: house,
+		log:          log,
+		listings:     listings,
+		ttl:          ttl,
+	}
+}
+
+func (h *House) HouseUser(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error) {
+	return h.cached(ctx, houseID, clientView, filter, h.houseStorage.HouseUser)
+}
+
+func (h *House) HouseAdmin(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error) {
+	return h.cached(ctx, houseID, moderatorView, filter, h.houseStorage.HouseAdmin)
+}
+
+func (h *House) UpdateHouse(ctx context.Context, houseID int, update models.HouseUpdate) (models.House, error) {
+	house, err := h.houseStorage.UpdateHouse(ctx, houseID, update)
+	if err == nil {
+		h.listings.invalidate(houseID)
+	}
+
+	return house, err
+}
+
+func (h *House) DeleteHouse(ctx context.Context, houseID int) error {
+	err := h.houseStorage.DeleteHouse(ctx, houseID)
+	if err == nil {
+		h.listings.invalidate(houseID)
+	}
+
+	return err
+}
+
+func (h *House) HardDeleteHouse(ctx context.Context, houseID int) error {
+	err := h.houseStorage.HardDeleteHouse(ctx, houseID)
+	if err == nil {
+		h.listings.invalidate(houseID)
+	}
+
+	return err
+}
+
+func (h *House) cached(
+	ctx context.Context,
+	houseID int,
+	view string,
+	filter models.FlatFilter,
+	load func(context.Context, int, models.FlatFilter) (models.FlatPage, error),
+) (models.FlatPage, error) {
+	const op = "storage.cache.House"
+
+	log := h.log.With(
+		slog.String("op", op),
+		slog.Int("house_id", houseID),
+	)
+
+	rawFilter, err := json.Marshal(filter)
+	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	generation := h.listings.generation(houseID)
+	key := listingKey(houseID, generation, view, rawFilter)
+
+	if raw, ok := h.listings.cache.Get(key); ok {
+		var page models.FlatPage
+		err := json.Unmarshal(raw, &page)
+		if err == nil {
+			return page, nil
+		}
+
+		log.Warn("failed to decode cached flats", sl.Err(err))
+	}
+
+	page, err := load(ctx, houseID, filter)
+	if err != nil {
+		return models.FlatPage{}, err
+	}
+
+	raw, err := json.Marshal(page)
+	if err != nil {
+		log.Warn("failed to encode flats for cache", sl.Err(err))
+
+		return page, nil
+	}
+
+	h.listings.set(houseID, generation, key, raw, h.ttl)
+
+	return page, nil
+}
+
+// Flat invalidates cached listings of the house whenever one of its flats changes.
+type Flat struct {
+	flatStorage
+	listings *Listings
+}
+
+func NewFlat(flat flatservice.Flat, listings *Listings) *Flat {
+	return &Flat{
+		flatStorage: flat,
+		listings:    listings,
+	}
+}
+
+func (f *Flat) SaveFlat(ctx context.Context, ownerID string, houseID, price, rooms int) (models.Flat, error) {
+	flat, err := f.flatStorage.SaveFlat(ctx, ownerID, houseID, price, rooms)
+	if err == nil {
+		f.invalidate(flat.HouseID)
+	}
+
+	return flat, err
+}
+
+func (f *Flat) UpdateFlat(ctx context.Context, update models.Flat, moderatorID, prevStatus string, lockExpiredBefore time.Time) (models.Flat, error) {
+	flat, err := f.flatStorage.UpdateFlat(ctx, update, moderatorID, prevStatus, lockExpiredBefore)
+	if err == nil {
+		f.invalidate(flat.HouseID)
+	}
+
+	return flat, err
+}
+
+func (f *Flat) UpdateOwnerFlat(ctx context.Context, update models.Flat, ownerID, prevStatus string) (models.Flat, error) {
+	flat, err := f.flatStorage.UpdateOwnerFlat(ctx, update, ownerID, prevStatus)
+	if err == nil {
+		f.invalidate(flat.HouseID)
+	}
+
+	return flat, err
+}
+
+func (f *Flat) TakeNext(ctx context.Context, moderatorID string, lockExpiredBefore time.Time) (models.Flat, error) {
+	flat, err := f.flatStorage.TakeNext(ctx, moderatorID, lockExpiredBefore)
+	if err == nil {
+		f.invalidate(flat.HouseID)
+	}
+
+	return flat, err
+}
+
+func (f *Flat) invalidate(houseID int) {
+	f.listings.invalidate(houseID)
+}
+
+func listingKey(houseID int, generation uint64, view string, filter []byte) string {
+	return fmt.Sprintf("house:%d:%d:%s:%s", houseID, generation, view, filter)
+}
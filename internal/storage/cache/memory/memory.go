@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type item struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// Cache is an in-process cache backend. Expired entries are dropped when they are read;
+// once the cache reaches its size limit the least recently used entries are evicted.
+type Cache struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List
+	maxEntries int
+}
+
+func New(maxEntries int) *Cache {
+	return &Cache{
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: maxEntries,
+	}
+}
+
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	it := el.Value.(*item)
+	if time.Now().After(it.expiresAt) {
+		c.remove(el)
+
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+
+	return it.value, true
+}
+
+func (c *Cache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+
+	if el, ok := c.items[key]; ok {
+		it := el.Value.(*item)
+		it.value = value
+		it.expiresAt = expiresAt
+		c.order.MoveToFront(el)
+
+		return
+	}
+
+	for c.maxEntries > 0 && len(c.items) >= c.maxEntries {
+		c.remove(c.order.Back())
+	}
+
+	c.items[key] = c.order.PushFront(&item{
+		key:       key,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*item).key)
+}
@@ -0,0 +1,82 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+)
+
+func (s *Storage) SaveUser(ctx context.Context, email, userType string, passHash []byte) (string, error) {
+	const op = "storage.memory.SaveUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.emails[strings.ToLower(email)]; ok {
+		return "", fmt.Errorf("%s: %w", op, errs.ErrUserExists)
+	}
+
+	id := uuid.NewString()
+
+	s.users[id] = models.User{
+		Id:       id,
+		Email:    email,
+		PassHash: append([]byte(nil), passHash...),
+	}
+	s.emails[strings.ToLower(email)] = id
+	if userType == constants.Admin {
+		s.admins[id] = true
+	}
+
+	return id, nil
+}
+
+func (s *Storage) User(ctx context.Context, userID string) (models.User, error) {
+	const op = "storage.memory.User"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, err := s.user(userID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) UserByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.memory.UserByEmail"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, err := s.user(s.emails[strings.ToLower(email)])
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// user returns the user with its type. The caller must hold the lock.
+func (s *Storage) user(userID string) (models.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return models.User{}, errs.ErrInvalidCredentials
+	}
+
+	if s.admins[userID] {
+		user.UserType = constants.Admin
+	} else {
+		user.UserType = constants.User
+	}
+
+	return user, nil
+}
@@ -0,0 +1,229 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+)
+
+func (s *Storage) SaveFlat(ctx context.Context, ownerID string, houseID, price, rooms int) (models.Flat, error) {
+	const op = "storage.memory.SaveFlat"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.houseExists(houseID) {
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+	}
+
+	number := 0
+	for _, flat := range s.flats {
+		if flat.HouseID == houseID && flat.FlatNumber > number {
+			number = flat.FlatNumber
+		}
+	}
+
+	s.lastFlatID++
+
+	now := time.Now()
+	flat := models.Flat{
+		ID:         s.lastFlatID,
+		HouseID:    houseID,
+		Price:      price,
+		Rooms:      rooms,
+		FlatNumber: number + 1,
+		Status:     constants.Created,
+		CreatedBy:  owner(ownerID),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	s.flats[flat.ID] = flat
+
+	return clone(flat), nil
+}
+
+// UpdateFlat updates the flat only if it is still in prevStatus and is not locked by
+// another moderator.
+func (s *Storage) UpdateFlat(ctx context.Context, update models.Flat, moderatorID, prevStatus string, lockExpiredBefore time.Time) (models.Flat, error) {
+	const op = "storage.memory.UpdateFlat"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flat, ok := s.flats[update.ID]
+	if !ok || !s.houseExists(flat.HouseID) {
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatNotFound)
+	}
+	if flat.Status != prevStatus {
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatTransition)
+	}
+	if flat.Status == constants.Moderation && !lockExpired(flat, lockExpiredBefore) && !flat.ModeratedBy(moderatorID) {
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatLocked)
+	}
+
+	flat.Status = update.Status
+	flat.UpdatedAt = time.Now()
+	flat.Price = update.Price
+	flat.Rooms = update.Rooms
+	flat.ModeratorID = clonePtr(update.ModeratorID)
+	flat.ModerationStartedAt = clonePtr(update.ModerationStartedAt)
+	flat.DeclineReason = clonePtr(update.DeclineReason)
+	flat.DeclineComment = clonePtr(update.DeclineComment)
+	s.flats[flat.ID] = flat
+
+	return clone(flat), nil
+}
+
+// Flat returns the flat. Flats of deleted houses are not found.
+func (s *Storage) Flat(ctx context.Context, flatID int) (models.Flat, error) {
+	const op = "storage.memory.Flat"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flat, ok := s.flats[flatID]
+	if !ok || !s.houseExists(flat.HouseID) {
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatNotFound)
+	}
+
+	return clone(flat), nil
+}
+
+// Queue returns flats waiting for moderation, oldest first.
+func (s *Storage) Queue(ctx context.Context, limit, offset int, lockExpiredBefore time.Time) ([]models.Flat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flats := s.waiting(lockExpiredBefore)
+	if offset >= len(flats) {
+		return []models.Flat{}, nil
+	}
+
+	flats = flats[offset:]
+	if len(flats) > limit {
+		flats = flats[:limit]
+	}
+
+	return flats, nil
+}
+
+// TakeNext moves the oldest waiting flat on moderation and locks it for the moderator.
+func (s *Storage) TakeNext(ctx context.Context, moderatorID string, lockExpiredBefore time.Time) (models.Flat, error) {
+	const op = "storage.memory.TakeNext"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waiting := s.waiting(lockExpiredBefore)
+	if len(waiting) == 0 {
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrQueueEmpty)
+	}
+
+	now := time.Now()
+
+	flat := s.flats[waiting[0].ID]
+	flat.Status = constants.Moderation
+	flat.ModeratorID = &moderatorID
+	flat.ModerationStartedAt = &now
+	flat.UpdatedAt = now
+	s.flats[flat.ID] = flat
+
+	return clone(flat), nil
+}
+
+func (s *Storage) OwnerFlats(ctx context.Context, ownerID string) ([]models.Flat, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flats := []models.Flat{}
+	for _, flat := range s.flats {
+		if flat.OwnedBy(ownerID) && s.houseExists(flat.HouseID) {
+			flats = append(flats, clone(flat))
+		}
+	}
+	sortByCreatedAt(flats)
+
+	return flats, nil
+}
+
+// UpdateOwnerFlat updates the flat on behalf of its owner. The moderation lock and
+// decline reason are dropped, since the flat has to be moderated again.
+func (s *Storage) UpdateOwnerFlat(ctx context.Context, update models.Flat, ownerID, prevStatus string) (models.Flat, error) {
+	const op = "storage.memory.UpdateOwnerFlat"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flat, ok := s.flats[update.ID]
+	if !ok || !s.houseExists(flat.HouseID) || !flat.OwnedBy(ownerID) || flat.Status != prevStatus {
+		return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatTransition)
+	}
+
+	flat.Status = update.Status
+	flat.UpdatedAt = time.Now()
+	flat.Price = update.Price
+	flat.Rooms = update.Rooms
+	flat.ModeratorID = nil
+	flat.ModerationStartedAt = nil
+	flat.DeclineReason = nil
+	flat.DeclineComment = nil
+	s.flats[flat.ID] = flat
+
+	return clone(flat), nil
+}
+
+// waiting returns created flats and flats with an expired moderation lock of houses
+// that are not deleted, oldest first. The caller must hold the lock.
+func (s *Storage) waiting(lockExpiredBefore time.Time) []models.Flat {
+	flats := []models.Flat{}
+	for _, flat := range s.flats {
+		if !s.houseExists(flat.HouseID) {
+			continue
+		}
+		if flat.Status == constants.Created || (flat.Status == constants.Moderation && lockExpired(flat, lockExpiredBefore)) {
+			flats = append(flats, clone(flat))
+		}
+	}
+	sortByCreatedAt(flats)
+
+	return flats
+}
+
+// owner mirrors the uuid owner column of the postgres storage: ids of dummy users
+// are not stored.
+func owner(ownerID string) *string {
+	if uuid.Validate(ownerID) != nil {
+		return nil
+	}
+
+	return &ownerID
+}
+
+// houseExists reports whether the house exists and is not deleted.
+// The caller must hold the lock.
+func (s *Storage) houseExists(houseID int) bool {
+	house, ok := s.houses[houseID]
+
+	return ok && house.DeletedAt == nil
+}
+
+func sortByCreatedAt(flats []models.Flat) {
+	sort.Slice(flats, func(i, j int) bool {
+		if !flats[i].CreatedAt.Equal(flats[j].CreatedAt) {
+			return flats[i].CreatedAt.Before(flats[j].CreatedAt)
+		}
+
+		return flats[i].ID < flats[j].ID
+	})
+}
+
+func lockExpired(flat models.Flat, expiredBefore time.Time) bool {
+	return flat.ModerationStartedAt == nil || flat.ModerationStartedAt.Before(expiredBefore)
+}
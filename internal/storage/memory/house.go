@@ -0,0 +1,377 @@
+package memstorage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+)
+
+const defaultLimit = 50
+
+func (s *Storage) SaveHouse(ctx context.Context, address, developer string, year int) (models.House, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastHouse++
+
+	now := time.Now()
+	house := models.House{
+		ID:        s.lastHouse,
+		Address:   address,
+		Year:      year,
+		Developer: developer,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.houses[house.ID] = house
+
+	return house, nil
+}
+
+// UpdateHouse changes the given fields of the house. Deleted houses are not found.
+func (s *Storage) UpdateHouse(ctx context.Context, houseID int, update models.HouseUpdate) (models.House, error) {
+	const op = "storage.memory.UpdateHouse"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	house, ok := s.houses[houseID]
+	if !ok || house.DeletedAt != nil {
+		return models.House{}, fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+	}
+
+	if update.Address != nil {
+		house.Address = *update.Address
+	}
+	if update.Year != nil {
+		house.Year = *update.Year
+	}
+	if update.Developer != nil {
+		house.Developer = *update.Developer
+	}
+	house.UpdatedAt = time.Now()
+
+	s.houses[houseID] = house
+
+	return house, nil
+}
+
+// DeleteHouse marks the house as deleted, keeping its flats.
+func (s *Storage) DeleteHouse(ctx context.Context, houseID int) error {
+	const op = "storage.memory.DeleteHouse"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	house, ok := s.houses[houseID]
+	if !ok || house.DeletedAt != nil {
+		return fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+	}
+
+	now := time.Now()
+	house.DeletedAt = &now
+	s.houses[houseID] = house
+
+	return nil
+}
+
+// HardDeleteHouse removes the house, deleted or not, together with its flats.
+func (s *Storage) HardDeleteHouse(ctx context.Context, houseID int) error {
+	const op = "storage.memory.HardDeleteHouse"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.houses[houseID]; !ok {
+		return fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+	}
+
+	for id, flat := range s.flats {
+		if flat.HouseID == houseID {
+			delete(s.flats, id)
+		}
+	}
+	delete(s.houses, houseID)
+
+	return nil
+}
+
+// HouseUser returns the approved flats of the house, visible to clients.
+func (s *Storage) HouseUser(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error) {
+	const op = "storage.memory.HouseUser"
+
+	filter.Status = constants.Approved
+
+	page, err := s.flatPage(houseID, filter)
+	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+func (s *Storage) HouseAdmin(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error) {
+	const op = "storage.memory.HouseAdmin"
+
+	page, err := s.flatPage(houseID, filter)
+	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+// SearchUser searches approved flats across all houses.
+func (s *Storage) SearchUser(ctx context.Context, filter models.FlatFilter) (models.FlatPage, error) {
+	const op = "storage.memory.SearchUser"
+
+	filter.Status = constants.Approved
+
+	page, err := s.flatPage(0, filter)
+	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+func (s *Storage) SearchAdmin(ctx context.Context, filter models.FlatFilter) (models.FlatPage, error) {
+	const op = "storage.memory.SearchAdmin"
+
+	page, err := s.flatPage(0, filter)
+	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+// Houses returns houses ordered by id with the number of approved flats and
+// their price range.
+func (s *Storage) Houses(ctx context.Context, filter models.HouseFilter) ([]models.HouseSummary, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	houses := []models.HouseSummary{}
+	for _, house := range s.houses {
+		if house.DeletedAt != nil {
+			continue
+		}
+		if filter.MinYear > 0 && house.Year < filter.MinYear {
+			continue
+		}
+		if filter.MaxYear > 0 && house.Year > filter.MaxYear {
+			continue
+		}
+		if filter.Developer != "" && !strings.EqualFold(house.Developer, filter.Developer) {
+			continue
+		}
+
+		houses = append(houses, models.HouseSummary{House: house})
+	}
+
+	sort.Slice(houses, func(i, j int) bool {
+		return houses[i].ID < houses[j].ID
+	})
+
+	if filter.Offset >= len(houses) {
+		return []models.HouseSummary{}, nil
+	}
+	houses = houses[filter.Offset:]
+	if len(houses) > filter.Limit {
+		houses = houses[:filter.Limit]
+	}
+
+	for i := range houses {
+		for _, flat := range s.flats {
+			if flat.HouseID != houses[i].ID || flat.Status != constants.Approved {
+				continue
+			}
+
+			price := flat.Price
+
+			houses[i].FlatsCount++
+			if houses[i].MinPrice == nil || price < *houses[i].MinPrice {
+				houses[i].MinPrice = &price
+			}
+			if houses[i].MaxPrice == nil || price > *houses[i].MaxPrice {
+				houses[i].MaxPrice = &price
+			}
+		}
+	}
+
+	return houses, nil
+}
+
+// flatPage mirrors the keyset pagination of the postgres house storage. Listing the
+// flats of a missing or deleted house fails with ErrHouseNotFound.
+func (s *Storage) flatPage(houseID int, filter models.FlatFilter) (models.FlatPage, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = models.SortByFlatNumber
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+
+	var after *models.Flat
+	if filter.Cursor != nil {
+		cursor, err := cursorFlat(filter.SortBy, *filter.Cursor)
+		if err != nil {
+			return models.FlatPage{}, fmt.Errorf("%w: %w", errs.ErrCursor, err)
+		}
+		after = &cursor
+	}
+
+	less := func(a, b models.Flat) bool {
+		if c := compare(a, b, filter.SortBy); c != 0 {
+			return (c < 0) != filter.Desc
+		}
+
+		return (a.ID < b.ID) != filter.Desc
+	}
+
+	s.mu.RLock()
+	if houseID != 0 && !s.houseExists(houseID) {
+		s.mu.RUnlock()
+
+		return models.FlatPage{}, errs.ErrHouseNotFound
+	}
+
+	flats := []models.Flat{}
+	for _, flat := range s.flats {
+		if houseID != 0 && flat.HouseID != houseID {
+			continue
+		}
+		if !s.matches(flat, filter) {
+			continue
+		}
+		if after != nil && !less(*after, flat) {
+			continue
+		}
+
+		flats = append(flats, clone(flat))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(flats, func(i, j int) bool {
+		return less(flats[i], flats[j])
+	})
+
+	page := models.FlatPage{Flats: flats}
+	if len(flats) > filter.Limit {
+		page.Flats = flats[:filter.Limit]
+
+		last := page.Flats[len(page.Flats)-1]
+		page.NextCursor = &models.FlatCursor{
+			SortBy: filter.SortBy,
+			Desc:   filter.Desc,
+			Value:  sortValue(last, filter.SortBy),
+			ID:     last.ID,
+		}
+	}
+
+	return page, nil
+}
+
+func (s *Storage) matches(flat models.Flat, filter models.FlatFilter) bool {
+	if filter.Status != "" && flat.Status != filter.Status {
+		return false
+	}
+	if filter.MinPrice > 0 && flat.Price < filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice > 0 && flat.Price > filter.MaxPrice {
+		return false
+	}
+	if filter.Rooms > 0 && flat.Rooms != filter.Rooms {
+		return false
+	}
+
+	house := s.houses[flat.HouseID]
+	if house.DeletedAt != nil {
+		return false
+	}
+	if filter.MinYear > 0 && house.Year < filter.MinYear {
+		return false
+	}
+	if filter.MaxYear > 0 && house.Year > filter.MaxYear {
+		return false
+	}
+	if filter.Developer != "" && !strings.EqualFold(house.Developer, filter.Developer) {
+		return false
+	}
+	if filter.Address != "" && !strings.Contains(strings.ToLower(house.Address), strings.ToLower(filter.Address)) {
+		return false
+	}
+
+	return true
+}
+
+// compare compares the flats by the sort column.
+func compare(a, b models.Flat, sortBy string) int {
+	switch sortBy {
+	case models.SortByPrice:
+		return a.Price - b.Price
+	case models.SortByRooms:
+		return a.Rooms - b.Rooms
+	case models.SortByCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	default:
+		return a.FlatNumber - b.FlatNumber
+	}
+}
+
+func sortValue(flat models.Flat, sortBy string) string {
+	switch sortBy {
+	case models.SortByPrice:
+		return strconv.Itoa(flat.Price)
+	case models.SortByRooms:
+		return strconv.Itoa(flat.Rooms)
+	case models.SortByCreatedAt:
+		return flat.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return strconv.Itoa(flat.FlatNumber)
+	}
+}
+
+// cursorFlat builds a flat holding the cursor position, so it can be compared with stored flats.
+func cursorFlat(sortBy string, cursor models.FlatCursor) (models.Flat, error) {
+	flat := models.Flat{ID: cursor.ID}
+
+	if sortBy == models.SortByCreatedAt {
+		createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return models.Flat{}, err
+		}
+		flat.CreatedAt = createdAt
+
+		return flat, nil
+	}
+
+	value, err := strconv.Atoi(cursor.Value)
+	if err != nil {
+		return models.Flat{}, err
+	}
+
+	switch sortBy {
+	case models.SortByPrice:
+		flat.Price = value
+	case models.SortByRooms:
+		flat.Rooms = value
+	default:
+		flat.FlatNumber = value
+	}
+
+	return flat, nil
+}
@@ -0,0 +1,64 @@
+package memstorage
+
+import (
+	"sync"
+
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	househandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/house"
+	authservice "github.com/zanzhit/flat-seller/internal/services/auth"
+	flatservice "github.com/zanzhit/flat-seller/internal/services/flat"
+)
+
+var (
+	_ authservice.UserSaver    = (*Storage)(nil)
+	_ authservice.UserProvider = (*Storage)(nil)
+	_ flatservice.Flat         = (*Storage)(nil)
+	_ househandler.House       = (*Storage)(nil)
+)
+
+// Storage keeps users, houses and flats in process memory. It implements the same
+// storage interfaces as the postgres storages with the same semantics, so services
+// and handlers can run without a database.
+type Storage struct {
+	mu sync.RWMutex
+
+	users  map[string]models.User
+	emails map[string]string // user ids by lower-case email
+	admins map[string]bool
+
+	houses     map[int]models.House
+	lastHouse  int
+	flats      map[int]models.Flat
+	lastFlatID int
+}
+
+func New() *Storage {
+	return &Storage{
+		users:  make(map[string]models.User),
+		emails: make(map[string]string),
+		admins: make(map[string]bool),
+		houses: make(map[int]models.House),
+		flats:  make(map[int]models.Flat),
+	}
+}
+
+// clone copies the flat, so callers cannot change the stored one through its pointer fields.
+func clone(flat models.Flat) models.Flat {
+	flat.CreatedBy = clonePtr(flat.CreatedBy)
+	flat.ModeratorID = clonePtr(flat.ModeratorID)
+	flat.ModerationStartedAt = clonePtr(flat.ModerationStartedAt)
+	flat.DeclineReason = clonePtr(flat.DeclineReason)
+	flat.DeclineComment = clonePtr(flat.DeclineComment)
+
+	return flat
+}
+
+func clonePtr[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+
+	c := *v
+
+	return &c
+}
@@ -0,0 +1,14 @@
+package memstorage_test
+
+import (
+	"testing"
+
+	memstorage "github.com/zanzhit/flat-seller/internal/storage/memory"
+	"github.com/zanzhit/flat-seller/internal/storage/storagetest"
+)
+
+func TestContract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		return memstorage.New()
+	})
+}
@@ -1,8 +1,10 @@
 package authstorage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/zanzhit/flat-seller/internal/domain/constants"
@@ -12,20 +14,26 @@ import (
 )
 
 type AuthStorage struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	timeout time.Duration
 }
 
-func New(db *sqlx.DB) *AuthStorage {
-	return &AuthStorage{db: db}
+func New(db *sqlx.DB, timeout time.Duration) *AuthStorage {
+	return &AuthStorage{
+		db:      db,
+		timeout: timeout,
+	}
 }
 
-func (s *AuthStorage) SaveUser(email, userType string, passHash []byte) (string, error) {
+func (s *AuthStorage) SaveUser(ctx context.Context, email, userType string, passHash []byte) (id string, err error) {
 	const op = "storage.postgres.auth.SaveUser"
 
-	var id string
 	query := fmt.Sprintf("INSERT INTO %s (email, password_hash) values ($1, $2) RETURNING id", postgres.UsersTable)
 
-	tx, err := s.db.Begin()
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
@@ -34,44 +42,71 @@ func (s *AuthStorage) SaveUser(email, userType string, passHash []byte) (string,
 		if err != nil {
 			tx.Rollback()
 		} else {
-			tx.Commit()
+			err = tx.Commit()
 		}
 	}()
 
-	row := tx.QueryRow(query, email, passHash)
-	if err := row.Scan(&id); err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
+	row := tx.QueryRowContext(ctx, query, email, passHash)
+	if err = row.Scan(&id); err != nil {
+		return "", fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
 	}
 
 	if userType == constants.Admin {
 		adminQuery := fmt.Sprintf("INSERT INTO %s (user_id) VALUES ($1)", postgres.AdminsTable)
-		if _, err := tx.Exec(adminQuery, id); err != nil {
-			return "", fmt.Errorf("%s: %w", op, err)
+		if _, err = tx.ExecContext(ctx, adminQuery, id); err != nil {
+			return "", fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
 		}
 	}
 
 	return id, nil
 }
 
-func (s *AuthStorage) User(userID string) (models.User, error) {
+func (s *AuthStorage) User(ctx context.Context, userID string) (models.User, error) {
 	const op = "storage.postgres.Auth.User"
 
-	var user models.User
 	query := fmt.Sprintf("SELECT id, email, password_hash FROM %s WHERE id = $1", postgres.UsersTable)
 
-	if err := s.db.Get(&user, query, userID); err != nil {
+	user, err := s.user(ctx, op, query, userID)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+// UserByEmail looks the user up through the unique index on lower(users.email), so
+// the case of the email does not matter.
+func (s *AuthStorage) UserByEmail(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.postgres.Auth.UserByEmail"
+
+	query := fmt.Sprintf("SELECT id, email, password_hash FROM %s WHERE lower(email) = lower($1)", postgres.UsersTable)
+
+	user, err := s.user(ctx, op, query, email)
+	if err != nil {
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *AuthStorage) user(ctx context.Context, op, query string, arg any) (models.User, error) {
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var user models.User
+	if err := s.db.GetContext(ctx, &user, query, arg); err != nil {
 		if err == sql.ErrNoRows {
-			return models.User{}, fmt.Errorf("%s: %w", op, errs.ErrInvalidCredentials)
+			return models.User{}, errs.ErrInvalidCredentials
 		}
-		return models.User{}, fmt.Errorf("%s: %w", op, err)
+		return models.User{}, err
 	}
 
 	query = fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE user_id = $1)", postgres.AdminsTable)
 	var isAdmin bool
 
-	err := s.db.Get(&isAdmin, query, userID)
+	err := s.db.GetContext(ctx, &isAdmin, query, user.Id)
 	if err != nil {
-		return models.User{}, fmt.Errorf("%s: %w", op, err)
+		return models.User{}, err
 	}
 
 	if isAdmin {
@@ -0,0 +1,55 @@
+package commentstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/storage/postgres"
+)
+
+type CommentStorage struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func New(db *sqlx.DB, timeout time.Duration) *CommentStorage {
+	return &CommentStorage{
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+func (s *CommentStorage) SaveComment(ctx context.Context, flatID int, authorID, text string) (models.Comment, error) {
+	const op = "storage.postgres.comment.SaveComment"
+
+	query := fmt.Sprintf("INSERT INTO %s (flat_id, author_id, text, created_at) VALUES ($1, $2, $3, $4) RETURNING *", postgres.FlatCommentsTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var comment models.Comment
+	if err := s.db.QueryRowxContext(ctx, query, flatID, authorID, text, time.Now()).StructScan(&comment); err != nil {
+		return models.Comment{}, fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
+	}
+
+	return comment, nil
+}
+
+func (s *CommentStorage) Comments(ctx context.Context, flatID int) ([]models.Comment, error) {
+	const op = "storage.postgres.comment.Comments"
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE flat_id = $1 ORDER BY created_at, id", postgres.FlatCommentsTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	comments := []models.Comment{}
+	if err := s.db.SelectContext(ctx, &comments, query, flatID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return comments, nil
+}
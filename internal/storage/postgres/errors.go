@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+)
+
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+)
+
+// constraintErrors maps constraint names to the domain errors they stand for.
+var constraintErrors = map[string]error{
+	"users_email_key":             errs.ErrUserExists,
+	"users_email_lower_key":       errs.ErrUserExists,
+	"admins_user_id_fkey":         errs.ErrUserNotFound,
+	"refresh_tokens_user_id_fkey": errs.ErrUserNotFound,
+	"flats_house_id_fkey":         errs.ErrHouseNotFound,
+	"subscriptions_house_id_fkey": errs.ErrHouseNotFound,
+	"flat_comments_flat_id_fkey":  errs.ErrFlatNotFound,
+	"flats_status_check":          errs.ErrFlatStatus,
+	"flats_decline_reason_check":  errs.ErrDeclineReason,
+}
+
+// TranslateError turns unique, foreign key and check violations into domain errors.
+// The constraint name picks the specific error; unknown constraints fall back to a
+// generic one by violation class. The driver error stays in the chain for logging.
+func TranslateError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	if domainErr, ok := constraintErrors[pqErr.Constraint]; ok {
+		return fmt.Errorf("%w: %w", domainErr, err)
+	}
+
+	switch pqErr.Code {
+	case uniqueViolation:
+		return fmt.Errorf("%w: %w", errs.ErrAlreadyExists, err)
+	case foreignKeyViolation:
+		return fmt.Errorf("%w: %w", errs.ErrReferenceNotFound, err)
+	case checkViolation:
+		return fmt.Errorf("%w: %w", errs.ErrConstraint, err)
+	default:
+		return err
+	}
+}
@@ -1,52 +1,246 @@
 package flatstorage
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
 	"github.com/zanzhit/flat-seller/internal/domain/models"
 	"github.com/zanzhit/flat-seller/internal/storage/postgres"
 )
 
 type FlatStorage struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	timeout time.Duration
 }
 
-func New(db *sqlx.DB) *FlatStorage {
-	return &FlatStorage{db: db}
+func New(db *sqlx.DB, timeout time.Duration) *FlatStorage {
+	return &FlatStorage{
+		db:      db,
+		timeout: timeout,
+	}
 }
 
-func (s *FlatStorage) SaveFlat(houseID, price, rooms int) (models.Flat, error) {
+func (s *FlatStorage) SaveFlat(ctx context.Context, ownerID string, houseID, price, rooms int) (models.Flat, error) {
 	const op = "storage.postgres.flat.SaveFlat"
 
+	// Deleted houses accept no new flats, so the insert selects nothing for them.
 	query := fmt.Sprintf(`
-		INSERT INTO %s (house_id, flat_number, price, rooms, status, created_at, updated_at)
-		VALUES ($1, (SELECT COALESCE(MAX(flat_number), 0) + 1 FROM %s WHERE house_id = $1), $2, $3, '%s', $4, $5)
-		RETURNING *`, postgres.FlatsTable, postgres.FlatsTable, constants.Created)
+		INSERT INTO %s (house_id, flat_number, price, rooms, status, created_by, created_at, updated_at)
+		SELECT $1, (SELECT COALESCE(MAX(flat_number), 0) + 1 FROM %s WHERE house_id = $1), $2::int, $3::int, '%s', $4::uuid, $5::timestamp, $6::timestamp
+		FROM %s WHERE id = $1 AND deleted_at IS NULL
+		RETURNING *`, postgres.FlatsTable, postgres.FlatsTable, constants.Created, postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
 
 	now := time.Now()
 	var flat models.Flat
-	err := s.db.QueryRowx(query, houseID, price, rooms, now, now).StructScan(&flat)
+	err := s.db.QueryRowxContext(ctx, query, houseID, price, rooms, owner(ownerID), now, now).StructScan(&flat)
 	if err != nil {
-		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+		}
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
 	}
 
 	return flat, nil
 }
 
-func (s *FlatStorage) UpdateFlat(flatID, price, rooms int, status string) (models.Flat, error) {
+// UpdateFlat updates the flat only if it is still in prevStatus and is not locked by
+// another moderator, so concurrent status changes cannot overwrite each other.
+// A flat that is still in prevStatus but was not updated is locked by another moderator.
+func (s *FlatStorage) UpdateFlat(ctx context.Context, update models.Flat, moderatorID, prevStatus string, lockExpiredBefore time.Time) (models.Flat, error) {
 	const op = "storage.postgres.flat.UpdateFlat"
 
-	query := fmt.Sprintf("UPDATE %s SET status = $1, updated_at = $2, price = $3, rooms = $4 WHERE id = $5 RETURNING *", postgres.FlatsTable)
+	query := fmt.Sprintf(`
+		UPDATE %s SET status = $1, updated_at = $2, price = $3, rooms = $4, moderator_id = $5, moderation_started_at = $6,
+			decline_reason = $7, decline_comment = $8
+		WHERE id = $9 AND status = $10
+		AND (status <> '%s' OR moderation_started_at IS NULL OR moderation_started_at < $12 OR moderator_id = $11)
+		AND house_id IN (SELECT id FROM %s WHERE deleted_at IS NULL)
+		RETURNING *`, postgres.FlatsTable, constants.Moderation, postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
 
-	now := time.Now()
 	var flat models.Flat
-	err := s.db.QueryRowx(query, status, now, price, rooms, flatID).StructScan(&flat)
+	err := s.db.QueryRowxContext(ctx, query,
+		update.Status, time.Now(), update.Price, update.Rooms, update.ModeratorID, update.ModerationStartedAt,
+		update.DeclineReason, update.DeclineComment, update.ID, prevStatus, moderatorID, lockExpiredBefore,
+	).StructScan(&flat)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Flat{}, fmt.Errorf("%s: %w", op, s.rejected(ctx, update.ID, prevStatus))
+		}
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
+	}
+
+	return flat, nil
+}
+
+// rejected tells why a guarded update matched no row: the flat or its house is gone,
+// its status has changed meanwhile, or it is locked by another moderator.
+func (s *FlatStorage) rejected(ctx context.Context, flatID int, prevStatus string) error {
+	query := fmt.Sprintf(`
+		SELECT f.status FROM %s f JOIN %s h ON h.id = f.house_id
+		WHERE f.id = $1 AND h.deleted_at IS NULL`, postgres.FlatsTable, postgres.HousesTable)
+
+	var status string
+	if err := s.db.GetContext(ctx, &status, query, flatID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.ErrFlatNotFound
+		}
+
+		return err
+	}
+
+	if status == prevStatus {
+		return errs.ErrFlatLocked
+	}
+
+	return errs.ErrFlatTransition
+}
+
+// Flat returns the flat. Flats of deleted houses are not found.
+func (s *FlatStorage) Flat(ctx context.Context, flatID int) (models.Flat, error) {
+	const op = "storage.postgres.flat.Flat"
+
+	query := fmt.Sprintf(`
+		SELECT f.* FROM %s f JOIN %s h ON h.id = f.house_id
+		WHERE f.id = $1 AND h.deleted_at IS NULL`, postgres.FlatsTable, postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var flat models.Flat
+	if err := s.db.GetContext(ctx, &flat, query, flatID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatNotFound)
+		}
+
 		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return flat, nil
 }
+
+// Queue returns flats waiting for moderation, oldest first. Flats whose moderation
+// lock has expired are waiting again.
+func (s *FlatStorage) Queue(ctx context.Context, limit, offset int, lockExpiredBefore time.Time) ([]models.Flat, error) {
+	const op = "storage.postgres.flat.Queue"
+
+	query := fmt.Sprintf(`
+		SELECT f.* FROM %s f JOIN %s h ON h.id = f.house_id
+		WHERE h.deleted_at IS NULL
+		AND (f.status = '%s' OR (f.status = '%s' AND (f.moderation_started_at IS NULL OR f.moderation_started_at < $1)))
+		ORDER BY f.created_at, f.id
+		LIMIT $2 OFFSET $3`, postgres.FlatsTable, postgres.HousesTable, constants.Created, constants.Moderation)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	flats := []models.Flat{}
+	if err := s.db.SelectContext(ctx, &flats, query, lockExpiredBefore, limit, offset); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+// TakeNext atomically moves the oldest waiting flat on moderation and locks it for the moderator.
+func (s *FlatStorage) TakeNext(ctx context.Context, moderatorID string, lockExpiredBefore time.Time) (models.Flat, error) {
+	const op = "storage.postgres.flat.TakeNext"
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET status = '%s', moderator_id = $1, moderation_started_at = $2, updated_at = $2
+		WHERE id = (
+			SELECT f.id FROM %s f JOIN %s h ON h.id = f.house_id
+			WHERE h.deleted_at IS NULL
+			AND (f.status = '%s' OR (f.status = '%s' AND (f.moderation_started_at IS NULL OR f.moderation_started_at < $3)))
+			ORDER BY f.created_at, f.id
+			LIMIT 1
+			FOR UPDATE OF f SKIP LOCKED
+		)
+		RETURNING *`, postgres.FlatsTable, constants.Moderation, postgres.FlatsTable, postgres.HousesTable, constants.Created, constants.Moderation)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var flat models.Flat
+	err := s.db.QueryRowxContext(ctx, query, moderatorID, time.Now(), lockExpiredBefore).StructScan(&flat)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrQueueEmpty)
+		}
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flat, nil
+}
+
+func (s *FlatStorage) OwnerFlats(ctx context.Context, ownerID string) ([]models.Flat, error) {
+	const op = "storage.postgres.flat.OwnerFlats"
+
+	query := fmt.Sprintf(`
+		SELECT f.* FROM %s f JOIN %s h ON h.id = f.house_id
+		WHERE f.created_by = $1 AND h.deleted_at IS NULL
+		ORDER BY f.created_at, f.id`, postgres.FlatsTable, postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	flats := []models.Flat{}
+	if err := s.db.SelectContext(ctx, &flats, query, owner(ownerID)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return flats, nil
+}
+
+// UpdateOwnerFlat updates the flat on behalf of its owner. The moderation lock and
+// decline reason are dropped, since the flat has to be moderated again.
+func (s *FlatStorage) UpdateOwnerFlat(ctx context.Context, update models.Flat, ownerID, prevStatus string) (models.Flat, error) {
+	const op = "storage.postgres.flat.UpdateOwnerFlat"
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET status = $1, updated_at = $2, price = $3, rooms = $4,
+			moderator_id = NULL, moderation_started_at = NULL, decline_reason = NULL, decline_comment = NULL
+		WHERE id = $5 AND created_by = $6 AND status = $7
+		AND house_id IN (SELECT id FROM %s WHERE deleted_at IS NULL)
+		RETURNING *`, postgres.FlatsTable, postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var flat models.Flat
+	err := s.db.QueryRowxContext(ctx, query, update.Status, time.Now(), update.Price, update.Rooms, update.ID, owner(ownerID), prevStatus).StructScan(&flat)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Flat{}, fmt.Errorf("%s: %w", op, errs.ErrFlatTransition)
+		}
+
+		return models.Flat{}, fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
+	}
+
+	return flat, nil
+}
+
+// owner returns the owner id as stored in the uuid created_by column. Dummy users
+// have no uuid, so their flats are stored without an owner and match no owner.
+func owner(ownerID string) *string {
+	if uuid.Validate(ownerID) != nil {
+		return nil
+	}
+
+	return &ownerID
+}
@@ -0,0 +1,64 @@
+package healthstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zanzhit/flat-seller/internal/storage/postgres"
+)
+
+type HealthStorage struct {
+	db              *sqlx.DB
+	timeout         time.Duration
+	migrationsTable string
+}
+
+func New(db *sqlx.DB, timeout time.Duration, migrationsTable string) *HealthStorage {
+	return &HealthStorage{
+		db:              db,
+		timeout:         timeout,
+		migrationsTable: migrationsTable,
+	}
+}
+
+func (s *HealthStorage) Ping(ctx context.Context) error {
+	const op = "storage.postgres.health.Ping"
+
+	ctx, done := postgres.StartQuery(ctx, op, "ping", s.timeout)
+	defer done()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MigrationVersion returns the schema version recorded by the migrator.
+// Zero means that no migration has been applied yet.
+func (s *HealthStorage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	const op = "storage.postgres.health.MigrationVersion"
+
+	query := fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", s.migrationsTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var row struct {
+		Version uint `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	if err := s.db.GetContext(ctx, &row, query); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return row.Version, row.Dirty, nil
+}
@@ -1,61 +1,386 @@
 package housestorage
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
 	"github.com/zanzhit/flat-seller/internal/domain/models"
 	"github.com/zanzhit/flat-seller/internal/storage/postgres"
 )
 
+const defaultLimit = 50
+
 type HouseStorage struct {
-	db *sqlx.DB
+	db      *sqlx.DB
+	timeout time.Duration
 }
 
-func New(db *sqlx.DB) *HouseStorage {
-	return &HouseStorage{db: db}
+func New(db *sqlx.DB, timeout time.Duration) *HouseStorage {
+	return &HouseStorage{
+		db:      db,
+		timeout: timeout,
+	}
 }
 
-func (s *HouseStorage) SaveHouse(address, developer string, year int) (models.House, error) {
+func (s *HouseStorage) SaveHouse(ctx context.Context, address, developer string, year int) (models.House, error) {
 	const op = "storage.postgres.house.SaveHouse"
 
 	query := fmt.Sprintf("INSERT INTO %s (address, year, developer, created_at) VALUES ($1, $2, $3, $4) RETURNING *", postgres.HousesTable)
 
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
 	var house models.House
-	err := s.db.QueryRowx(query, address, year, developer, time.Now()).StructScan(&house)
+	err := s.db.QueryRowxContext(ctx, query, address, year, developer, time.Now()).StructScan(&house)
 	if err != nil {
-		return house, fmt.Errorf("%s: %w", op, err)
+		return house, fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
 	}
 
 	return house, nil
 }
 
-func (s *HouseStorage) HouseUser(houseID int) ([]models.Flat, error) {
+// UpdateHouse changes the given fields of the house. Deleted houses are not found.
+func (s *HouseStorage) UpdateHouse(ctx context.Context, houseID int, update models.HouseUpdate) (models.House, error) {
+	const op = "storage.postgres.house.UpdateHouse"
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET address = COALESCE($1, address), year = COALESCE($2, year), developer = COALESCE($3, developer), updated_at = $4
+		WHERE id = $5 AND deleted_at IS NULL
+		RETURNING *`, postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var house models.House
+	err := s.db.QueryRowxContext(ctx, query, update.Address, update.Year, update.Developer, time.Now(), houseID).StructScan(&house)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.House{}, fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+		}
+
+		return models.House{}, fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
+	}
+
+	return house, nil
+}
+
+// DeleteHouse marks the house as deleted. Its flats and subscriptions are kept, but
+// the house and its flats disappear from listings and accept no new flats or subscribers.
+func (s *HouseStorage) DeleteHouse(ctx context.Context, houseID int) error {
+	const op = "storage.postgres.house.DeleteHouse"
+
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL", postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	res, err := s.db.ExecContext(ctx, query, time.Now(), houseID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+	}
+
+	return nil
+}
+
+// HardDeleteHouse removes the house, deleted or not, together with its subscriptions
+// and flats; comments go with the flats. The foreign keys restrict deleting a house
+// with dependents, so they are removed here explicitly in one transaction.
+func (s *HouseStorage) HardDeleteHouse(ctx context.Context, houseID int) (err error) {
+	const op = "storage.postgres.house.HardDeleteHouse"
+
+	queries := []string{
+		fmt.Sprintf("DELETE FROM %s WHERE house_id = $1", postgres.SubscriptionsTable),
+		fmt.Sprintf("DELETE FROM %s WHERE house_id = $1", postgres.FlatsTable),
+		fmt.Sprintf("DELETE FROM %s WHERE id = $1", postgres.HousesTable),
+	}
+
+	// The span covers the whole transaction, so it carries every statement of it.
+	ctx, done := postgres.StartQuery(ctx, op, strings.Join(queries, ";\n"), s.timeout)
+	defer done()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = errors.Join(err, fmt.Errorf("%s: rollback: %w", op, rbErr))
+			}
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	for _, query := range queries[:2] {
+		if _, err = tx.ExecContext(ctx, query, houseID); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, queries[2], houseID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+	}
+
+	return nil
+}
+
+// HouseUser returns the approved flats of the house, visible to clients.
+func (s *HouseStorage) HouseUser(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error) {
 	const op = "storage.postgres.house.HouseUser"
 
-	query := fmt.Sprintf("SELECT * FROM %s WHERE house_id = $1 AND status = '%s'", postgres.FlatsTable, constants.Approved)
+	filter.Status = constants.Approved
 
-	var flats []models.Flat
-	err := s.db.Select(&flats, query, houseID)
+	page, err := s.flats(ctx, houseID, filter)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return flats, nil
+	return page, nil
 }
 
-func (s *HouseStorage) HouseAdmin(houseID int) ([]models.Flat, error) {
+func (s *HouseStorage) HouseAdmin(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error) {
 	const op = "storage.postgres.house.HouseModerator"
 
-	query := fmt.Sprintf("SELECT * FROM %s WHERE house_id = $1", postgres.FlatsTable)
+	page, err := s.flats(ctx, houseID, filter)
+	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+// SearchUser searches approved flats across all houses.
+func (s *HouseStorage) SearchUser(ctx context.Context, filter models.FlatFilter) (models.FlatPage, error) {
+	const op = "storage.postgres.house.SearchUser"
+
+	filter.Status = constants.Approved
 
-	var flats []models.Flat
-	err := s.db.Select(&flats, query, houseID)
+	page, err := s.flats(ctx, 0, filter)
 	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+func (s *HouseStorage) SearchAdmin(ctx context.Context, filter models.FlatFilter) (models.FlatPage, error) {
+	const op = "storage.postgres.house.SearchAdmin"
+
+	page, err := s.flats(ctx, 0, filter)
+	if err != nil {
+		return models.FlatPage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return page, nil
+}
+
+// Houses returns houses ordered by id with the number of approved flats and
+// their price range.
+func (s *HouseStorage) Houses(ctx context.Context, filter models.HouseFilter) ([]models.HouseSummary, error) {
+	const op = "storage.postgres.house.Houses"
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+
+	args := []any{constants.Approved}
+	conds := []string{"h.deleted_at IS NULL"}
+
+	if filter.MinYear > 0 {
+		args = append(args, filter.MinYear)
+		conds = append(conds, fmt.Sprintf("h.year >= $%d", len(args)))
+	}
+	if filter.MaxYear > 0 {
+		args = append(args, filter.MaxYear)
+		conds = append(conds, fmt.Sprintf("h.year <= $%d", len(args)))
+	}
+	if filter.Developer != "" {
+		args = append(args, filter.Developer)
+		conds = append(conds, fmt.Sprintf("lower(h.developer) = lower($%d)", len(args)))
+	}
+
+	query := fmt.Sprintf(`SELECT h.*, count(f.id) AS flats_count, min(f.price) AS min_price, max(f.price) AS max_price
+		FROM %s h
+		LEFT JOIN %s f ON f.house_id = h.id AND f.status = $1
+		WHERE %s
+		GROUP BY h.id
+		ORDER BY h.id
+		LIMIT %d OFFSET %d`,
+		postgres.HousesTable, postgres.FlatsTable, strings.Join(conds, " AND "), filter.Limit, filter.Offset)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	houses := []models.HouseSummary{}
+	if err := s.db.SelectContext(ctx, &houses, query, args...); err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return flats, nil
+	return houses, nil
+}
+
+var sortColumns = map[string]string{
+	models.SortByPrice:      "f.price",
+	models.SortByRooms:      "f.rooms",
+	models.SortByFlatNumber: "f.flat_number",
+	models.SortByCreatedAt:  "f.created_at",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// flats returns one page of flats of the house, or of all houses if houseID is zero.
+// Pages are addressed by a keyset cursor on the sort column and the flat id, so deep
+// pages are as cheap as the first one.
+func (s *HouseStorage) flats(ctx context.Context, houseID int, filter models.FlatFilter) (models.FlatPage, error) {
+	column, ok := sortColumns[filter.SortBy]
+	if !ok {
+		filter.SortBy = models.SortByFlatNumber
+		column = sortColumns[filter.SortBy]
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+
+	conds := []string{"h.deleted_at IS NULL"}
+	var args []any
+	where := func(cond string, values ...any) {
+		placeholders := make([]any, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = len(args)
+		}
+		conds = append(conds, fmt.Sprintf(cond, placeholders...))
+	}
+
+	if houseID != 0 {
+		where("f.house_id = $%d", houseID)
+	}
+	if filter.Status != "" {
+		where("f.status = $%d", filter.Status)
+	}
+	if filter.MinPrice > 0 {
+		where("f.price >= $%d", filter.MinPrice)
+	}
+	if filter.MaxPrice > 0 {
+		where("f.price <= $%d", filter.MaxPrice)
+	}
+	if filter.Rooms > 0 {
+		where("f.rooms = $%d", filter.Rooms)
+	}
+	if filter.MinYear > 0 {
+		where("h.year >= $%d", filter.MinYear)
+	}
+	if filter.MaxYear > 0 {
+		where("h.year <= $%d", filter.MaxYear)
+	}
+	if filter.Developer != "" {
+		where("lower(h.developer) = lower($%d)", filter.Developer)
+	}
+	if filter.Address != "" {
+		where("h.address ILIKE '%%' || $%d || '%%'", likeEscaper.Replace(filter.Address))
+	}
+
+	order, cmp := "ASC", ">"
+	if filter.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	if filter.Cursor != nil {
+		where(fmt.Sprintf("(%s, f.id) %s ($%%d, $%%d)", column, cmp), filter.Cursor.Value, filter.Cursor.ID)
+	}
+
+	// The house is joined even without house filters to hide flats of deleted houses.
+	query := fmt.Sprintf("SELECT f.* FROM %s f JOIN %s h ON h.id = f.house_id WHERE %s ORDER BY %s %s, f.id %s LIMIT %d",
+		postgres.FlatsTable, postgres.HousesTable, strings.Join(conds, " AND "), column, order, order, filter.Limit+1)
+
+	ctx, done := postgres.StartQuery(ctx, "storage.postgres.house.flats", query, s.timeout)
+	defer done()
+
+	flats := []models.Flat{}
+	if err := s.db.SelectContext(ctx, &flats, query, args...); err != nil {
+		return models.FlatPage{}, err
+	}
+
+	// An empty page of one house tells a house without matching flats from a missing one.
+	if houseID != 0 && len(flats) == 0 {
+		if err := s.houseExists(ctx, houseID); err != nil {
+			return models.FlatPage{}, err
+		}
+	}
+
+	page := models.FlatPage{Flats: flats}
+	if len(flats) > filter.Limit {
+		page.Flats = flats[:filter.Limit]
+
+		last := page.Flats[len(page.Flats)-1]
+		page.NextCursor = &models.FlatCursor{
+			SortBy: filter.SortBy,
+			Desc:   filter.Desc,
+			Value:  sortValue(last, filter.SortBy),
+			ID:     last.ID,
+		}
+	}
+
+	return page, nil
+}
+
+func sortValue(flat models.Flat, sortBy string) string {
+	switch sortBy {
+	case models.SortByPrice:
+		return strconv.Itoa(flat.Price)
+	case models.SortByRooms:
+		return strconv.Itoa(flat.Rooms)
+	case models.SortByCreatedAt:
+		return flat.CreatedAt.Format(time.RFC3339Nano)
+	default:
+		return strconv.Itoa(flat.FlatNumber)
+	}
+}
+
+// houseExists returns ErrHouseNotFound unless the house exists and is not deleted.
+func (s *HouseStorage) houseExists(ctx context.Context, houseID int) error {
+	const op = "storage.postgres.house.houseExists"
+
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND deleted_at IS NULL)", postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var exists bool
+	if err := s.db.GetContext(ctx, &exists, query, houseID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return errs.ErrHouseNotFound
+	}
+
+	return nil
 }
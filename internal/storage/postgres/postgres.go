@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
@@ -19,7 +20,10 @@ func New(cfg config.Config) (*sqlx.DB, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	err = db.Ping()
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DB.QueryTimeout)
+	defer cancel()
+
+	err = db.PingContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -0,0 +1,33 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	authstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/auth"
+	flatstorage "github.com/zanzhit/flat-seller/internal/storage/postgres/flat"
+	housestorage "github.com/zanzhit/flat-seller/internal/storage/postgres/house"
+	"github.com/zanzhit/flat-seller/internal/storage/postgres/postgrestest"
+	"github.com/zanzhit/flat-seller/internal/storage/storagetest"
+)
+
+const queryTimeout = 5 * time.Second
+
+// storage puts the postgres storages together behind the interfaces of the contract.
+type storage struct {
+	*authstorage.AuthStorage
+	*flatstorage.FlatStorage
+	*housestorage.HouseStorage
+}
+
+func TestContract(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		db := postgrestest.New(t)
+
+		return storage{
+			AuthStorage:  authstorage.New(db, queryTimeout),
+			FlatStorage:  flatstorage.New(db, queryTimeout),
+			HouseStorage: housestorage.New(db, queryTimeout),
+		}
+	})
+}
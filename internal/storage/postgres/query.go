@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/zanzhit/flat-seller/internal/storage/postgres")
+
+// StartQuery starts a client span for the SQL query executed by the storage operation
+// and bounds it with the query timeout. A zero timeout leaves the context deadline as is.
+// The returned function must be called when the query is done.
+func StartQuery(ctx context.Context, op, query string, timeout time.Duration) (context.Context, func()) {
+	cancel := context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	ctx, span := tracer.Start(ctx, op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", query),
+		),
+	)
+
+	return ctx, func() {
+		span.End()
+		cancel()
+	}
+}
@@ -0,0 +1,68 @@
+package subscriptionstorage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/storage/postgres"
+)
+
+type SubscriptionStorage struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func New(db *sqlx.DB, timeout time.Duration) *SubscriptionStorage {
+	return &SubscriptionStorage{
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+func (s *SubscriptionStorage) Subscribe(ctx context.Context, houseID int, email string) error {
+	const op = "storage.postgres.subscription.Subscribe"
+
+	// Deleted houses accept no subscribers; the query reports whether the house is there.
+	query := fmt.Sprintf(`
+		WITH house AS (SELECT id FROM %s WHERE id = $1 AND deleted_at IS NULL),
+		inserted AS (
+			INSERT INTO %s (house_id, email, created_at) SELECT id, $2, $3::timestamp FROM house
+			ON CONFLICT (house_id, email) DO NOTHING
+		)
+		SELECT EXISTS (SELECT 1 FROM house)`, postgres.HousesTable, postgres.SubscriptionsTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var found bool
+	if err := s.db.GetContext(ctx, &found, query, houseID, email, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
+	}
+
+	if !found {
+		return fmt.Errorf("%s: %w", op, errs.ErrHouseNotFound)
+	}
+
+	return nil
+}
+
+func (s *SubscriptionStorage) Subscribers(ctx context.Context, houseID int) ([]string, error) {
+	const op = "storage.postgres.subscription.Subscribers"
+
+	query := fmt.Sprintf(`
+		SELECT s.email FROM %s s JOIN %s h ON h.id = s.house_id
+		WHERE s.house_id = $1 AND h.deleted_at IS NULL`, postgres.SubscriptionsTable, postgres.HousesTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var emails []string
+	if err := s.db.SelectContext(ctx, &emails, query, houseID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return emails, nil
+}
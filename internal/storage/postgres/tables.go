@@ -1,8 +1,12 @@
 package postgres
 
 const (
-	UsersTable  = "users"
-	AdminsTable = "admins"
-	FlatsTable  = "flats"
-	HousesTable = "houses"
+	UsersTable         = "users"
+	AdminsTable        = "admins"
+	FlatsTable         = "flats"
+	HousesTable        = "houses"
+	SubscriptionsTable = "subscriptions"
+	RefreshTokensTable = "refresh_tokens"
+	RevokedTokensTable = "revoked_tokens"
+	FlatCommentsTable  = "flat_comments"
 )
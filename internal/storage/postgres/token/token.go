@@ -0,0 +1,181 @@
+package tokenstorage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	"github.com/zanzhit/flat-seller/internal/storage/postgres"
+)
+
+type TokenStorage struct {
+	db      *sqlx.DB
+	timeout time.Duration
+}
+
+func New(db *sqlx.DB, timeout time.Duration) *TokenStorage {
+	return &TokenStorage{
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+func (s *TokenStorage) SaveRefreshToken(ctx context.Context, tokenHash []byte, token models.RefreshToken) error {
+	const op = "storage.postgres.token.SaveRefreshToken"
+
+	query := fmt.Sprintf("INSERT INTO %s (token_hash, user_id, access_jti, expires_at, created_at) VALUES ($1, $2, $3, $4, $5)", postgres.RefreshTokensTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	if _, err := s.db.ExecContext(ctx, query, tokenHash, token.UserID, token.AccessJTI, token.ExpiresAt, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, postgres.TranslateError(err))
+	}
+
+	return nil
+}
+
+// TakeRefreshToken deletes the refresh token and returns it, so every token can be used only once.
+func (s *TokenStorage) TakeRefreshToken(ctx context.Context, tokenHash []byte) (models.RefreshToken, error) {
+	const op = "storage.postgres.token.TakeRefreshToken"
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE token_hash = $1 RETURNING user_id, access_jti, expires_at", postgres.RefreshTokensTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var token models.RefreshToken
+	if err := s.db.QueryRowxContext(ctx, query, tokenHash).StructScan(&token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RefreshToken{}, fmt.Errorf("%s: %w", op, errs.ErrInvalidToken)
+		}
+
+		return models.RefreshToken{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return token, nil
+}
+
+// RevokeToken puts the access token into the denylist and removes the refresh tokens issued with it.
+func (s *TokenStorage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) (err error) {
+	const op = "storage.postgres.token.RevokeToken"
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE access_jti = $1", postgres.RefreshTokensTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = errors.Join(err, fmt.Errorf("%s: rollback: %w", op, rbErr))
+			}
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, query, jti); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", postgres.RevokedTokensTable)
+	if _, err = tx.ExecContext(ctx, query, jti, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE expires_at < $1", postgres.RevokedTokensTable)
+	if _, err = tx.ExecContext(ctx, query, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RevokeUserTokens puts every access token of the user into the denylist, removes
+// the user's refresh tokens and records the time of revocation, so access tokens
+// issued until now are rejected even if no refresh token records their jti.
+func (s *TokenStorage) RevokeUserTokens(ctx context.Context, userID string, expiresAt time.Time) (err error) {
+	const op = "storage.postgres.token.RevokeUserTokens"
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (jti, expires_at)
+		SELECT access_jti, $2 FROM %s WHERE user_id = $1
+		ON CONFLICT (jti) DO NOTHING`, postgres.RevokedTokensTable, postgres.RefreshTokensTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = errors.Join(err, fmt.Errorf("%s: rollback: %w", op, rbErr))
+			}
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, query, userID, expiresAt); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE user_id = $1", postgres.RefreshTokensTable)
+	if _, err = tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	query = fmt.Sprintf("UPDATE %s SET tokens_revoked_at = $2 WHERE id = $1", postgres.UsersTable)
+	if _, err = tx.ExecContext(ctx, query, userID, time.Now()); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the access token is in the denylist or was issued before
+// the tokens of its user were revoked.
+func (s *TokenStorage) IsRevoked(ctx context.Context, jti, userID string, issuedAt time.Time) (bool, error) {
+	const op = "storage.postgres.token.IsRevoked"
+
+	query := fmt.Sprintf(`
+		SELECT EXISTS (SELECT 1 FROM %s WHERE jti = $1)
+		OR EXISTS (SELECT 1 FROM %s WHERE id = $2 AND tokens_revoked_at >= $3)`,
+		postgres.RevokedTokensTable, postgres.UsersTable)
+
+	ctx, done := postgres.StartQuery(ctx, op, query, s.timeout)
+	defer done()
+
+	var revoked bool
+	if err := s.db.GetContext(ctx, &revoked, query, jti, user(userID), issuedAt); err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return revoked, nil
+}
+
+// user returns the user id as stored in the uuid id column. Dummy users are not
+// stored and cannot be revoked.
+func user(userID string) *string {
+	if uuid.Validate(userID) != nil {
+		return nil
+	}
+
+	return &userID
+}
@@ -0,0 +1,427 @@
+// Package storagetest is a contract test suite for the storages of users, houses and
+// flats. The memory and postgres storages run it, so both keep the same semantics.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zanzhit/flat-seller/internal/domain/constants"
+	"github.com/zanzhit/flat-seller/internal/domain/errs"
+	"github.com/zanzhit/flat-seller/internal/domain/models"
+	househandler "github.com/zanzhit/flat-seller/internal/http-server/handlers/house"
+	authservice "github.com/zanzhit/flat-seller/internal/services/auth"
+	flatservice "github.com/zanzhit/flat-seller/internal/services/flat"
+)
+
+type Storage interface {
+	authservice.UserSaver
+	authservice.UserProvider
+	flatservice.Flat
+	househandler.House
+}
+
+// Run runs the suite. newStorage is called once per test and must return an empty storage.
+func Run(t *testing.T, newStorage func(t *testing.T) Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s Storage)
+	}{
+		{"UniqueEmail", testUniqueEmail},
+		{"UserType", testUserType},
+		{"FlatNumbering", testFlatNumbering},
+		{"Visibility", testVisibility},
+		{"DeletedHouse", testDeletedHouse},
+		{"HardDeletedHouse", testHardDeletedHouse},
+		{"ModerationLock", testModerationLock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+func testUniqueEmail(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	if _, err := s.SaveUser(ctx, "seller@example.com", constants.User, []byte("hash")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.SaveUser(ctx, "seller@example.com", constants.Admin, []byte("hash"))
+	if !errors.Is(err, errs.ErrUserExists) {
+		t.Fatalf("expected ErrUserExists, got %v", err)
+	}
+
+	_, err = s.SaveUser(ctx, "Seller@Example.com", constants.User, []byte("hash"))
+	if !errors.Is(err, errs.ErrUserExists) {
+		t.Fatalf("email in another case: expected ErrUserExists, got %v", err)
+	}
+
+	user, err := s.UserByEmail(ctx, "SELLER@example.com")
+	if err != nil {
+		t.Fatalf("email in another case: %v", err)
+	}
+	if user.Email != "seller@example.com" {
+		t.Fatalf("expected seller@example.com, got %s", user.Email)
+	}
+}
+
+func testUserType(t *testing.T, s Storage) {
+	ctx := context.Background()
+
+	for _, userType := range []string{constants.User, constants.Admin} {
+		email := userType + "@example.com"
+
+		id, err := s.SaveUser(ctx, email, userType, []byte("hash"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		byID, err := s.User(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byEmail, err := s.UserByEmail(ctx, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, user := range []models.User{byID, byEmail} {
+			if user.Id != id || user.Email != email || user.UserType != userType || string(user.PassHash) != "hash" {
+				t.Fatalf("unexpected user: %+v", user)
+			}
+		}
+	}
+
+	if _, err := s.UserByEmail(ctx, "missing@example.com"); !errors.Is(err, errs.ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func testFlatNumbering(t *testing.T, s Storage) {
+	ctx := context.Background()
+	seller := saveUser(t, s, constants.User)
+	first := saveHouse(t, s)
+	second := saveHouse(t, s)
+
+	for i, houseID := range []int{first, first, second, first, second} {
+		flat, err := s.SaveFlat(ctx, seller, houseID, 1000, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []int{1, 2, 1, 3, 2}[i]
+		if flat.FlatNumber != want || flat.HouseID != houseID || flat.Status != constants.Created {
+			t.Fatalf("flat %d: expected number %d in house %d, got %+v", i, want, houseID, flat)
+		}
+		if flat.CreatedBy == nil || *flat.CreatedBy != seller {
+			t.Fatalf("flat %d: expected owner %s, got %v", i, seller, flat.CreatedBy)
+		}
+	}
+
+	if _, err := s.SaveFlat(ctx, seller, second+1, 1000, 2); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("expected ErrHouseNotFound, got %v", err)
+	}
+}
+
+func testVisibility(t *testing.T, s Storage) {
+	ctx := context.Background()
+	seller := saveUser(t, s, constants.User)
+	moderator := saveUser(t, s, constants.Admin)
+	houseID := saveHouse(t, s)
+
+	approved := saveFlat(t, s, seller, houseID, 1000)
+	moderate(t, s, approved, moderator, constants.Approved)
+	declined := saveFlat(t, s, seller, houseID, 2000)
+	moderate(t, s, declined, moderator, constants.Declined)
+	created := saveFlat(t, s, seller, houseID, 3000)
+
+	assertFlats(t, "house for clients", houseUser(t, s, houseID), approved)
+	assertFlats(t, "house for moderators", houseAdmin(t, s, houseID), approved, declined, created)
+
+	page, err := s.SearchUser(ctx, models.FlatFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlats(t, "search for clients", page.Flats, approved)
+
+	page, err = s.SearchAdmin(ctx, models.FlatFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlats(t, "search for moderators", page.Flats, approved, declined, created)
+
+	houses, err := s.Houses(ctx, models.HouseFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(houses) != 1 || houses[0].FlatsCount != 1 || houses[0].MinPrice == nil || *houses[0].MinPrice != 1000 {
+		t.Fatalf("expected one house counting only the approved flat, got %+v", houses)
+	}
+
+	owned, err := s.OwnerFlats(ctx, seller)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlats(t, "flats of the seller", owned, approved, declined, created)
+
+	owned, err = s.OwnerFlats(ctx, moderator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlats(t, "flats of the moderator", owned)
+
+	// A house without visible flats is listed empty, a missing one is not found.
+	emptyID := saveHouse(t, s)
+	assertFlats(t, "empty house", houseUser(t, s, emptyID))
+	if _, err := s.HouseUser(ctx, emptyID+1, models.FlatFilter{}); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("missing house: expected ErrHouseNotFound, got %v", err)
+	}
+}
+
+func testDeletedHouse(t *testing.T, s Storage) {
+	ctx := context.Background()
+	seller := saveUser(t, s, constants.User)
+	moderator := saveUser(t, s, constants.Admin)
+	houseID := saveHouse(t, s)
+
+	approved := saveFlat(t, s, seller, houseID, 1000)
+	moderate(t, s, approved, moderator, constants.Approved)
+	created := saveFlat(t, s, seller, houseID, 2000)
+
+	if err := s.DeleteHouse(ctx, houseID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteHouse(ctx, houseID); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("second delete: expected ErrHouseNotFound, got %v", err)
+	}
+
+	if _, err := s.Flat(ctx, approved.ID); !errors.Is(err, errs.ErrFlatNotFound) {
+		t.Fatalf("expected ErrFlatNotFound, got %v", err)
+	}
+	if _, err := s.SaveFlat(ctx, seller, houseID, 1000, 2); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("expected ErrHouseNotFound, got %v", err)
+	}
+	if _, err := s.UpdateHouse(ctx, houseID, models.HouseUpdate{}); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("expected ErrHouseNotFound, got %v", err)
+	}
+
+	update := created
+	update.Status = constants.Moderation
+	if _, err := s.UpdateFlat(ctx, update, moderator, constants.Created, time.Now()); !errors.Is(err, errs.ErrFlatNotFound) {
+		t.Fatalf("expected ErrFlatNotFound, got %v", err)
+	}
+	if _, err := s.TakeNext(ctx, moderator, time.Now()); !errors.Is(err, errs.ErrQueueEmpty) {
+		t.Fatalf("expected ErrQueueEmpty, got %v", err)
+	}
+
+	queue, err := s.Queue(ctx, 10, 0, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlats(t, "queue", queue)
+
+	if _, err := s.HouseUser(ctx, houseID, models.FlatFilter{}); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("house for clients: expected ErrHouseNotFound, got %v", err)
+	}
+	if _, err := s.HouseAdmin(ctx, houseID, models.FlatFilter{}); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("house for moderators: expected ErrHouseNotFound, got %v", err)
+	}
+
+	owned, err := s.OwnerFlats(ctx, seller)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlats(t, "flats of the seller", owned)
+
+	houses, err := s.Houses(ctx, models.HouseFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(houses) != 0 {
+		t.Fatalf("expected no houses, got %+v", houses)
+	}
+}
+
+func testHardDeletedHouse(t *testing.T, s Storage) {
+	ctx := context.Background()
+	seller := saveUser(t, s, constants.User)
+	houseID := saveHouse(t, s)
+	flat := saveFlat(t, s, seller, houseID, 1000)
+
+	if err := s.DeleteHouse(ctx, houseID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.HardDeleteHouse(ctx, houseID); err != nil {
+		t.Fatalf("hard delete of a deleted house: %v", err)
+	}
+	if err := s.HardDeleteHouse(ctx, houseID); !errors.Is(err, errs.ErrHouseNotFound) {
+		t.Fatalf("expected ErrHouseNotFound, got %v", err)
+	}
+	if _, err := s.Flat(ctx, flat.ID); !errors.Is(err, errs.ErrFlatNotFound) {
+		t.Fatalf("expected ErrFlatNotFound, got %v", err)
+	}
+}
+
+func testModerationLock(t *testing.T, s Storage) {
+	ctx := context.Background()
+	seller := saveUser(t, s, constants.User)
+	moderator := saveUser(t, s, constants.Admin)
+	another := "another-moderator"
+	houseID := saveHouse(t, s)
+
+	approved := saveFlat(t, s, seller, houseID, 1000)
+	moderate(t, s, approved, moderator, constants.Approved)
+	declined := saveFlat(t, s, seller, houseID, 2000)
+	moderate(t, s, declined, moderator, constants.Declined)
+	locked := saveFlat(t, s, seller, houseID, 3000)
+	moderate(t, s, locked, moderator, constants.Moderation)
+
+	// The moderator who decided stays on the flat, but does not lock it.
+	for _, flat := range []models.Flat{approved, declined} {
+		got, err := s.Flat(ctx, flat.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.ModeratedBy(moderator) {
+			t.Fatalf("flat %d: expected moderator %s, got %v", flat.ID, moderator, got.ModeratorID)
+		}
+	}
+
+	reconsidered, err := s.Flat(ctx, declined.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	reconsidered.Status = constants.Moderation
+	reconsidered.ModeratorID = &another
+	reconsidered.ModerationStartedAt = &now
+	if _, err := s.UpdateFlat(ctx, reconsidered, another, constants.Declined, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("declined flat: expected another moderator to take it, got %v", err)
+	}
+
+	update, err := s.Flat(ctx, locked.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update.Status = constants.Approved
+	update.ModeratorID = &another
+	update.ModerationStartedAt = nil
+	if _, err := s.UpdateFlat(ctx, update, another, constants.Moderation, now.Add(-time.Hour)); !errors.Is(err, errs.ErrFlatLocked) {
+		t.Fatalf("locked flat: expected ErrFlatLocked, got %v", err)
+	}
+	if _, err := s.UpdateFlat(ctx, update, another, constants.Moderation, now.Add(time.Hour)); err != nil {
+		t.Fatalf("expired lock: expected another moderator to take it over, got %v", err)
+	}
+
+	// Approved and declined flats keep their moderator, but are not waiting.
+	queue, err := s.Queue(ctx, 10, 0, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertFlats(t, "queue", queue)
+}
+
+func saveUser(t *testing.T, s Storage, userType string) string {
+	t.Helper()
+
+	id, err := s.SaveUser(context.Background(), userType+"@example.com", userType, []byte("hash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return id
+}
+
+func saveHouse(t *testing.T, s Storage) int {
+	t.Helper()
+
+	house, err := s.SaveHouse(context.Background(), "Lenina 1", "Developer", 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return house.ID
+}
+
+func saveFlat(t *testing.T, s Storage, ownerID string, houseID, price int) models.Flat {
+	t.Helper()
+
+	flat, err := s.SaveFlat(context.Background(), ownerID, houseID, price, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return flat
+}
+
+// moderate moves the created flat to the status the way a moderator does.
+func moderate(t *testing.T, s Storage, flat models.Flat, moderatorID, status string) {
+	t.Helper()
+
+	now := time.Now()
+	flat.Status = status
+	flat.ModeratorID = &moderatorID
+	if status == constants.Moderation {
+		flat.ModerationStartedAt = &now
+	}
+	if status == constants.Declined {
+		reason := constants.ReasonDuplicate
+		flat.DeclineReason = &reason
+	}
+
+	if _, err := s.UpdateFlat(context.Background(), flat, moderatorID, constants.Created, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func houseUser(t *testing.T, s Storage, houseID int) []models.Flat {
+	t.Helper()
+
+	page, err := s.HouseUser(context.Background(), houseID, models.FlatFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return page.Flats
+}
+
+func houseAdmin(t *testing.T, s Storage, houseID int) []models.Flat {
+	t.Helper()
+
+	page, err := s.HouseAdmin(context.Background(), houseID, models.FlatFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return page.Flats
+}
+
+// assertFlats checks that got holds the wanted flats in order, compared by id.
+func assertFlats(t *testing.T, what string, got []models.Flat, want ...models.Flat) {
+	t.Helper()
+
+	ids := func(flats []models.Flat) []int {
+		ids := make([]int, 0, len(flats))
+		for _, flat := range flats {
+			ids = append(ids, flat.ID)
+		}
+
+		return ids
+	}
+
+	gotIDs, wantIDs := ids(got), ids(want)
+	if len(gotIDs) != len(wantIDs) {
+		t.Fatalf("%s: expected flats %v, got %v", what, wantIDs, gotIDs)
+	}
+	for i := range gotIDs {
+		if gotIDs[i] != wantIDs[i] {
+			t.Fatalf("%s: expected flats %v, got %v", what, wantIDs, gotIDs)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_house_id_fkey;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_house_id_fkey FOREIGN KEY (house_id) REFERENCES houses(id) ON DELETE CASCADE;
+
+ALTER TABLE flats DROP CONSTRAINT IF EXISTS flats_house_id_fkey;
+ALTER TABLE flats ADD CONSTRAINT flats_house_id_fkey FOREIGN KEY (house_id) REFERENCES houses(id) ON DELETE CASCADE;
+
+-- Without deleted_at soft-deleted houses would come back, so they are deleted for
+-- good together with their flats, comments and subscriptions.
+DELETE FROM houses WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE houses DROP COLUMN IF EXISTS deleted_at;
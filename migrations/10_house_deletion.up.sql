@@ -0,0 +1,9 @@
+ALTER TABLE houses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Hard deletion removes flats and subscriptions of the house explicitly, so the
+-- database refuses to drop them implicitly. Flat comments still go with their flat.
+ALTER TABLE flats DROP CONSTRAINT IF EXISTS flats_house_id_fkey;
+ALTER TABLE flats ADD CONSTRAINT flats_house_id_fkey FOREIGN KEY (house_id) REFERENCES houses(id) ON DELETE RESTRICT;
+
+ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS subscriptions_house_id_fkey;
+ALTER TABLE subscriptions ADD CONSTRAINT subscriptions_house_id_fkey FOREIGN KEY (house_id) REFERENCES houses(id) ON DELETE RESTRICT;
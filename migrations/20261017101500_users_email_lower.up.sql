@@ -0,0 +1,3 @@
+-- Emails are unique regardless of case. Creating the index fails if existing
+-- users differ only in the case of their email; they have to be merged first.
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_key ON users (lower(email));
@@ -0,0 +1 @@
+DROP TABLE subscriptions;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE flats
+    DROP COLUMN IF EXISTS moderation_started_at,
+    DROP COLUMN IF EXISTS moderator_id;
@@ -0,0 +1,3 @@
+ALTER TABLE flats
+    ADD COLUMN IF NOT EXISTS moderator_id VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS moderation_started_at TIMESTAMP;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS flats_status_created_at_idx;
\ No newline at end of file
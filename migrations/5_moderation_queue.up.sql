@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS flats_status_created_at_idx ON flats (status, created_at, id);
@@ -0,0 +1,5 @@
+DROP TABLE flat_comments;
+
+ALTER TABLE flats
+    DROP COLUMN IF EXISTS decline_comment,
+    DROP COLUMN IF EXISTS decline_reason;
@@ -0,0 +1,13 @@
+ALTER TABLE flats
+    ADD COLUMN IF NOT EXISTS decline_reason VARCHAR(50) CHECK (decline_reason IN ('incorrect_price', 'incorrect_data', 'duplicate', 'prohibited_content', 'other')),
+    ADD COLUMN IF NOT EXISTS decline_comment TEXT;
+
+CREATE TABLE IF NOT EXISTS flat_comments (
+    id SERIAL PRIMARY KEY,
+    flat_id INT NOT NULL REFERENCES flats(id) ON DELETE CASCADE,
+    author_id VARCHAR(64) NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS flat_comments_flat_id_idx ON flat_comments (flat_id, created_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS flats_created_by_idx;
+
+UPDATE flats SET status = 'created' WHERE status = 'withdrawn';
+
+ALTER TABLE flats DROP CONSTRAINT IF EXISTS flats_status_check;
+ALTER TABLE flats ADD CONSTRAINT flats_status_check CHECK (status IN ('created', 'approved', 'declined', 'on moderation'));
+
+ALTER TABLE flats DROP COLUMN IF EXISTS created_by;
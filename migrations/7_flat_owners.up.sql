@@ -0,0 +1,7 @@
+-- Flats created before owners were recorded have no owner.
+ALTER TABLE flats ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE flats DROP CONSTRAINT IF EXISTS flats_status_check;
+ALTER TABLE flats ADD CONSTRAINT flats_status_check CHECK (status IN ('created', 'approved', 'declined', 'on moderation', 'withdrawn'));
+
+CREATE INDEX IF NOT EXISTS flats_created_by_idx ON flats (created_by, created_at);
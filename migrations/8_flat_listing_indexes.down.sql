@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS flats_house_status_idx;
+
+DROP INDEX IF EXISTS flats_house_created_at_idx;
+
+DROP INDEX IF EXISTS flats_house_rooms_idx;
+
+DROP INDEX IF EXISTS flats_house_price_idx;
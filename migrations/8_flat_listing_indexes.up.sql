@@ -0,0 +1,7 @@
+CREATE INDEX IF NOT EXISTS flats_house_price_idx ON flats (house_id, price, id);
+
+CREATE INDEX IF NOT EXISTS flats_house_rooms_idx ON flats (house_id, rooms, id);
+
+CREATE INDEX IF NOT EXISTS flats_house_created_at_idx ON flats (house_id, created_at, id);
+
+CREATE INDEX IF NOT EXISTS flats_house_status_idx ON flats (house_id, status);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS flats_status_price_idx;
+
+DROP INDEX IF EXISTS houses_developer_idx;
+
+DROP INDEX IF EXISTS houses_address_trgm_idx;
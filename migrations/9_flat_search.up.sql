@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS houses_address_trgm_idx ON houses USING gin (address gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS houses_developer_idx ON houses (lower(developer));
+
+CREATE INDEX IF NOT EXISTS flats_status_price_idx ON flats (status, price, id);